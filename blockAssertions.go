@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// blockAssertion is a `# @assert <subject> <operator> [value]` directive, checked
+// after the response is read:
+//
+//	# @assert status == 201
+//	# @assert status in 200..299
+//	# @assert header Content-Type matches ^application/json
+//	# @assert $.user.id > 0
+//	# @assert body contains "created"
+//	# @assert duration < 500ms
+//	# @assert content-length <= 1024
+//
+// The subject is status, header <name>, body, duration, content-length or a JSONPath.
+// The operators are == != < <= > >= contains matches in and exists.
+type blockAssertion struct {
+	Text     string // as written after @assert
+	Subject  string
+	Header   string // header name when the subject is header
+	Operator string
+	Value    string
+	Err      error // the assertion can't be parsed, reported as failed
+	regex    *regexp.Regexp
+}
+
+// assertionResult is the outcome of a blockAssertion or a client.test of a script for one response
+type assertionResult struct {
+	Name   string // the assertion as written or the name of the test
+	Passed bool
+	Got    string
+}
+
+var assertionOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"contains": true, "matches": true, "in": true, "exists": true,
+}
+
+// parseAssertion reads the text after `@assert`
+func parseAssertion(text string) blockAssertion {
+	assertion := blockAssertion{Text: text}
+
+	subject, rest := cutField(text)
+	switch {
+	case subject == "header":
+		assertion.Header, rest = cutField(rest)
+		if assertion.Header == "" {
+			assertion.Err = fmt.Errorf("missing header name")
+			return assertion
+		}
+	case subject == "status", subject == "body", subject == "duration", subject == "content-length":
+	case strings.HasPrefix(subject, "$"):
+	case subject == "":
+		assertion.Err = fmt.Errorf("empty assertion")
+		return assertion
+	default:
+		assertion.Err = fmt.Errorf("unknown subject %q", subject)
+		return assertion
+	}
+	assertion.Subject = subject
+
+	assertion.Operator, rest = cutField(rest)
+	if !assertionOperators[assertion.Operator] {
+		assertion.Err = fmt.Errorf("unknown operator %q", assertion.Operator)
+		return assertion
+	}
+
+	value := strings.TrimSpace(rest)
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+	}
+	assertion.Value = value
+
+	if assertion.Operator == "exists" {
+		if value != "" {
+			assertion.Err = fmt.Errorf("exists takes no value")
+		}
+		return assertion
+	}
+	if value == "" {
+		assertion.Err = fmt.Errorf("missing value after %s", assertion.Operator)
+		return assertion
+	}
+
+	switch assertion.Operator {
+	case "matches":
+		regex, err := regexp.Compile(value)
+		if err != nil {
+			assertion.Err = fmt.Errorf("invalid regex: %w", err)
+		}
+		assertion.regex = regex
+	case "in":
+		if _, _, err := assertion.parseRange(); err != nil {
+			assertion.Err = err
+		}
+	case "<", "<=", ">", ">=":
+		if _, err := assertion.number(value); err != nil {
+			assertion.Err = err
+		}
+	case "==", "!=":
+		if assertion.Subject == "duration" || assertion.Subject == "content-length" {
+			if _, err := assertion.number(value); err != nil {
+				assertion.Err = err
+			}
+		}
+	}
+	return assertion
+}
+
+// cutField returns the first word of text and what follows it
+func cutField(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, " \t"); i != -1 {
+		return text[:i], text[i+1:]
+	}
+	return text, ""
+}
+
+// number reads a value compared with the subject, durations are in ms unless a unit is given
+func (a blockAssertion) number(value string) (float64, error) {
+	if a.Subject == "duration" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return float64(d.Milliseconds()), nil
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return n, nil
+}
+
+// parseRange reads `200..299`, both ends included
+func (a blockAssertion) parseRange() (float64, float64, error) {
+	low, high, ok := strings.Cut(a.Value, "..")
+	if !ok {
+		return 0, 0, fmt.Errorf("range must be min..max: %s", a.Value)
+	}
+	from, err := a.number(strings.TrimSpace(low))
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := a.number(strings.TrimSpace(high))
+	if err != nil {
+		return 0, 0, err
+	}
+	return from, to, nil
+}
+
+// evaluateAssertions checks the assertions of a block against its response
+func evaluateAssertions(assertions []blockAssertion, resp *http.Response, body []byte, elapsed time.Duration) []assertionResult {
+	results := make([]assertionResult, 0, len(assertions))
+	for _, assertion := range assertions {
+		results = append(results, evaluateAssertion(assertion, resp, body, elapsed))
+	}
+	return results
+}
+
+func evaluateAssertion(a blockAssertion, resp *http.Response, body []byte, elapsed time.Duration) assertionResult {
+	result := assertionResult{Name: a.Text}
+	if a.Err != nil {
+		result.Got = a.Err.Error()
+		return result
+	}
+
+	// The value of the subject, as text and as a JSON value for JSONPaths
+	var got string
+	var value any
+	exists := true
+	switch a.Subject {
+	case "status":
+		got = strconv.Itoa(resp.StatusCode)
+	case "header":
+		values, ok := resp.Header[http.CanonicalHeaderKey(a.Header)]
+		exists = ok
+		got = strings.Join(values, ", ")
+	case "body":
+		got = string(body)
+	case "duration":
+		got = strconv.FormatInt(elapsed.Milliseconds(), 10)
+	case "content-length":
+		got = strconv.Itoa(len(body))
+	default:
+		data, err := decodeJSON(body)
+		if err != nil {
+			result.Got = "body is not JSON"
+			return result
+		}
+		value, err = evaluateJSONPath(data, a.Subject)
+		exists = err == nil
+		got = formatJSONValue(value)
+	}
+
+	result.Got = got
+	if a.Subject == "body" {
+		result.Got = truncate(strings.TrimSpace(got), 80)
+	}
+	if a.Operator == "exists" {
+		result.Passed = exists
+		return result
+	}
+	// Only exists passes without a value, so `$.typo != x` doesn't pass silently
+	if !exists {
+		result.Got = "missing"
+		if a.Subject != "header" {
+			result.Got = "path not found"
+		}
+		return result
+	}
+
+	switch a.Operator {
+	case "contains":
+		if list, ok := value.([]any); ok {
+			for _, item := range list {
+				if formatJSONValue(item) == a.Value {
+					result.Passed = true
+				}
+			}
+		} else {
+			result.Passed = strings.Contains(got, a.Value)
+		}
+	case "matches":
+		result.Passed = a.regex.MatchString(got)
+	case "in":
+		from, to, _ := a.parseRange()
+		n, err := strconv.ParseFloat(got, 64)
+		result.Passed = err == nil && n >= from && n <= to
+	case "==", "!=":
+		result.Passed = assertionEqual(a, got) == (a.Operator == "==")
+	default:
+		n, err := strconv.ParseFloat(got, 64)
+		expected, _ := a.number(a.Value)
+		if err != nil {
+			break
+		}
+		switch a.Operator {
+		case "<":
+			result.Passed = n < expected
+		case "<=":
+			result.Passed = n <= expected
+		case ">":
+			result.Passed = n > expected
+		case ">=":
+			result.Passed = n >= expected
+		}
+	}
+	return result
+}
+
+// assertionEqual compares numbers by value, `2xx` with the status class and everything else as text
+func assertionEqual(a blockAssertion, got string) bool {
+	if a.Subject == "status" {
+		if class, ok := statusClass(a.Value); ok {
+			code, _ := strconv.Atoi(got)
+			return code/100 == class
+		}
+	}
+	if x, err := strconv.ParseFloat(got, 64); err == nil {
+		if y, err := a.number(a.Value); err == nil {
+			return x == y
+		}
+	}
+	return got == a.Value
+}
+
+func assertionsPassed(results []assertionResult) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// printAssertionResults prints one line per assertion below the block result
+func printAssertionResults(results []assertionResult) {
+	for _, result := range results {
+		if result.Passed {
+			fmt.Printf("%s    [✓] %s%s\n", C_Green, result.Name, C_Reset)
+		} else {
+			fmt.Printf("%s    [X] %s Got: [ %s ]%s\n", C_Red, result.Name, result.Got, C_Reset)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEvaluateAssertion(t *testing.T) {
+	resp := &http.Response{
+		Status:     "201 Created",
+		StatusCode: 201,
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+	}
+	body := []byte(`{"message": "user created", "user": {"id": 42, "name": "Ada", "roles": ["admin", "dev"]}}`)
+	elapsed := 120 * time.Millisecond
+
+	tests := map[string]bool{
+		"status == 201":      true,
+		"status == 2xx":      true,
+		"status != 200":      true,
+		"status in 200..299": true,
+		"status in 400..499": false,
+		"status >= 400":      false,
+		"header Content-Type matches ^application/json": true,
+		"header content-type contains charset":          true,
+		"header Location exists":                        false,
+		"header Content-Type == text/plain":             false,
+		"$.user.id > 0":                                 true,
+		"$.user.id == 42.0":                             true,
+		`$.user.name == "Ada"`:                          true,
+		"$.user.roles contains admin":                   true,
+		"$.user.roles contains root":                    false,
+		"$.user.email exists":                           false,
+		"$.user.email == x":                             false,
+		"$.user.email != x":                             false,
+		"$.user.emial contains @":                       false,
+		"header Location != /users/42":                  false,
+		`body contains "user created"`:                  true,
+		"body matches ^\\{":                             true,
+		"duration < 500ms":                              true,
+		"duration < 100":                                false,
+		"duration <= 1s":                                true,
+		"content-length in 10..1024":                    true,
+		"content-length < 10":                           false,
+		"size == 10":                                    false,
+		"status ~ 201":                                  false,
+		"$.user.id > many":                              false,
+		"header Content-Type matches [":                 false,
+	}
+
+	for text, expected := range tests {
+		result := evaluateAssertion(parseAssertion(text), resp, body, elapsed)
+		if result.Passed != expected {
+			t.Errorf("assertion %q expected: %v, Got: %v (%s)", text, expected, result.Passed, result.Got)
+		}
+	}
+
+	if result := evaluateAssertion(parseAssertion("$.user.email != x"), resp, body, elapsed); result.Got != "path not found" {
+		t.Errorf("Incorrect result of a missing path. expected: path not found, Got: %s", result.Got)
+	}
+}
+
+func TestParseAssertionErrors(t *testing.T) {
+	for _, text := range []string{"", "status", "size == 10", "status ~ 201", "header", "$.id exists 1", "status in 200", "duration < soon"} {
+		if assertion := parseAssertion(text); assertion.Err == nil {
+			t.Errorf("expected an error for %q, Got: %+v", text, assertion)
+		}
+	}
+}
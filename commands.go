@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runCommand runs a command typed in the terminal while watching:
+//
+//	env            prints the selected environment
+//	env <name>     switches to another environment and sends everything again
+//	forget [name]  asks the `// @prompt` variables again, all of them without a name
+func runCommand(config *Config, line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+
+	switch fields[0] {
+	case "env":
+		// The environment is read while a run is prepared, under the lock of runs
+		if len(fields) == 1 {
+			var environment string
+			runs.locked(func() { environment = config.Environment })
+			fmt.Printf("%senvironment: %q%s\n", C_Gray, environment, C_Reset)
+			return
+		}
+		// Both the switch and its undo run in the prepare step of runs.restart
+		_, err := reloadAndSend(config, func() func() {
+			previous := config.Environment
+			config.Environment = fields[1]
+			return func() { config.Environment = previous }
+		})
+		if err != nil {
+			fmt.Printf("%sError switching environment: %v%s\n", C_Red, err, C_Reset)
+		}
+	case "forget":
+		forgetPromptAnswers(fields[1:]...)
+		fmt.Printf("%sprompts will be asked again on the next run%s\n", C_Gray, C_Reset)
+	default:
+		fmt.Printf("%sunknown command %q, try: env <name>, forget [name]%s\n", C_Yellow, fields[0], C_Reset)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// blockRef is a block by the index of its file and its index in the file
+type blockRef struct {
+	file  int
+	block int
+}
+
+// plannedBlock is a block of the run in the order it's sent
+type plannedBlock struct {
+	blockRef
+	dependsOn []int // plan indexes of the blocks it needs, it's skipped when one of them doesn't pass
+	after     []int // plan indexes that must be finished before it's sent: dependsOn and the block before it in its file
+}
+
+// blockDependencies returns the names of the blocks a block needs: its `// @depends-on` and
+// the blocks its request variables read, like {{login.response.body.$.token}}
+func blockDependencies(block HTTPBlock) (explicit, inferred []string) {
+	explicit = block.DependsOn
+
+	texts := []string{block.Request.Url, block.Request.Body}
+	for _, value := range block.Request.Headers {
+		texts = append(texts, value)
+	}
+	for _, text := range texts {
+		for _, match := range reRequestVariable.FindAllStringSubmatch(text, -1) {
+			if name := match[1]; name != block.Name && !slices.Contains(inferred, name) {
+				inferred = append(inferred, name)
+			}
+		}
+	}
+	slices.Sort(inferred)
+	return explicit, inferred
+}
+
+// blockLabel names a block in errors: users.http block 2 (login)
+func blockLabel(httpFileContentParsed []HTTPFileContent, ref blockRef) string {
+	fileContent := httpFileContentParsed[ref.file]
+	block := fileContent.Blocks[ref.block]
+	label := fmt.Sprintf("%s block %d", filepath.Base(fileContent.FilePath), block.ID)
+	if block.Name != "" {
+		label += " (" + block.Name + ")"
+	}
+	return label
+}
+
+// planBlocks orders the blocks so each one comes after the blocks it depends on, keeping the
+// order of the files otherwise. With only, the plan has just those blocks (a `// @name` or
+// file.http:id) and what they need. sequential chains every block to the one before it,
+// otherwise only the blocks of a file without `// @parallel` are chained.
+func planBlocks(httpFileContentParsed []HTTPFileContent, only []string, sequential bool) ([]plannedBlock, error) {
+	var refs []blockRef
+	named := make(map[string][]int) // indexes in refs by `// @name`
+	for i, fileContent := range httpFileContentParsed {
+		for j, block := range fileContent.Blocks {
+			if block.Name != "" {
+				named[block.Name] = append(named[block.Name], len(refs))
+			}
+			refs = append(refs, blockRef{file: i, block: j})
+		}
+	}
+
+	dependencies := make([][]int, len(refs))
+	for i, ref := range refs {
+		explicit, inferred := blockDependencies(httpFileContentParsed[ref.file].Blocks[ref.block])
+		for _, name := range explicit {
+			if _, ok := named[name]; !ok {
+				return nil, fmt.Errorf("%s: @depends-on %s, no block has // @name %s", blockLabel(httpFileContentParsed, ref), name, name)
+			}
+		}
+		// Variables of unknown blocks are reported when the block is sent
+		for _, name := range append(slices.Clone(explicit), inferred...) {
+			for _, dependency := range named[name] {
+				if dependency != i && !slices.Contains(dependencies[i], dependency) {
+					dependencies[i] = append(dependencies[i], dependency)
+				}
+			}
+		}
+	}
+
+	selected := make([]bool, len(refs))
+	if len(only) == 0 {
+		for i := range selected {
+			selected[i] = true
+		}
+	}
+	var selectWithDependencies func(i int)
+	selectWithDependencies = func(i int) {
+		if selected[i] {
+			return
+		}
+		selected[i] = true
+		for _, dependency := range dependencies[i] {
+			selectWithDependencies(dependency)
+		}
+	}
+	for _, name := range only {
+		indexes, err := findBlocks(httpFileContentParsed, refs, named, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range indexes {
+			selectWithDependencies(i)
+		}
+	}
+
+	// Kahn's algorithm, always taking the first block in file order that is ready
+	waiting := make([]int, len(refs))
+	dependants := make([][]int, len(refs))
+	for i := range refs {
+		if !selected[i] {
+			continue
+		}
+		waiting[i] = len(dependencies[i])
+		for _, dependency := range dependencies[i] {
+			dependants[dependency] = append(dependants[dependency], i)
+		}
+	}
+	planIndex := make([]int, len(refs))
+	done := make([]bool, len(refs))
+	var order []int
+	for {
+		next := -1
+		for i := range refs {
+			if selected[i] && !done[i] && waiting[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		done[next] = true
+		planIndex[next] = len(order)
+		order = append(order, next)
+		for _, dependant := range dependants[next] {
+			waiting[dependant]--
+		}
+	}
+
+	for i := range refs {
+		if selected[i] && !done[i] {
+			return nil, dependencyCycle(httpFileContentParsed, refs, dependencies, done, i)
+		}
+	}
+
+	plan := make([]plannedBlock, len(order))
+	lastOfFile := make(map[int]int) // plan index of the last block of each file
+	for p, i := range order {
+		planned := plannedBlock{blockRef: refs[i]}
+		for _, dependency := range dependencies[i] {
+			planned.dependsOn = append(planned.dependsOn, planIndex[dependency])
+		}
+		slices.Sort(planned.dependsOn)
+		planned.after = slices.Clone(planned.dependsOn)
+
+		previous, ok := lastOfFile[refs[i].file]
+		if sequential && p > 0 {
+			previous, ok = p-1, true
+		} else if httpFileContentParsed[refs[i].file].Parallel {
+			ok = false
+		}
+		if ok && !slices.Contains(planned.after, previous) {
+			planned.after = append(planned.after, previous)
+		}
+		lastOfFile[refs[i].file] = p
+		plan[p] = planned
+	}
+	return plan, nil
+}
+
+// findBlocks finds the blocks of --only: a `// @name` or file.http:id
+func findBlocks(httpFileContentParsed []HTTPFileContent, refs []blockRef, named map[string][]int, name string) ([]int, error) {
+	if indexes, ok := named[name]; ok {
+		return indexes, nil
+	}
+	if fileName, id, ok := strings.Cut(name, ":"); ok {
+		for i, ref := range refs {
+			fileContent := httpFileContentParsed[ref.file]
+			if filepath.Base(fileContent.FilePath) == fileName && strconv.Itoa(fileContent.Blocks[ref.block].ID) == id {
+				return []int{i}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("--only %s: no block has // @name %s, use the name or file.http:id of a block", name, name)
+}
+
+// dependencyCycle follows the dependencies from a block that couldn't be planned until one repeats
+func dependencyCycle(httpFileContentParsed []HTTPFileContent, refs []blockRef, dependencies [][]int, done []bool, start int) error {
+	var path []int
+	seen := make(map[int]int) // position in path
+	for i := start; ; {
+		if position, ok := seen[i]; ok {
+			var labels []string
+			for _, j := range append(path[position:], i) {
+				labels = append(labels, blockLabel(httpFileContentParsed, refs[j]))
+			}
+			return fmt.Errorf("dependency cycle: %s", strings.Join(labels, " -> "))
+		}
+		seen[i] = len(path)
+		path = append(path, i)
+		// A block left out of the plan always has a dependency left out as well
+		for _, dependency := range dependencies[i] {
+			if !done[dependency] {
+				i = dependency
+				break
+			}
+		}
+	}
+}
+
+// checkBlockDependencies makes sure the blocks can be ordered before anything is sent
+func checkBlockDependencies(httpFileContent []HTTPFileContent, config *Config) ([]HTTPFileContent, error) {
+	if _, err := planBlocks(httpFileContent, config.Only, false); err != nil {
+		return nil, err
+	}
+	return httpFileContent, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeHTTPFiles writes the .http files in a temporary folder and parses them
+func writeHTTPFiles(t *testing.T, config *Config, files map[string]string) ([]HTTPFileContent, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config.HTTPFolderPath = dir
+	return processHTTPFiles(config)
+}
+
+func planLabels(httpFileContent []HTTPFileContent, plan []plannedBlock) string {
+	var labels []string
+	for _, planned := range plan {
+		labels = append(labels, blockLabel(httpFileContent, planned.blockRef))
+	}
+	return strings.Join(labels, ", ")
+}
+
+func TestPlanBlocks(t *testing.T) {
+	files := map[string]string{
+		"a.http": "// @depends-on login\nGET http://localhost/me HTTP/1.1\n###\n// @name login\nPOST http://localhost/login HTTP/1.1",
+		"b.http": "// @name create\nPOST http://localhost/users HTTP/1.1\nAuthorization: Bearer {{login.response.body.$.token}}\n###\n// @depends-on create\nGET http://localhost/users/1 HTTP/1.1\n###\nGET http://localhost/health HTTP/1.1",
+	}
+	httpFileContent, err := writeHTTPFiles(t, &Config{}, files)
+	if err != nil {
+		t.Fatalf("got error on function processHTTPFiles: %v", err)
+	}
+
+	plan, err := planBlocks(httpFileContent, nil, true)
+	if err != nil {
+		t.Fatalf("got error on function planBlocks: %v", err)
+	}
+	expected := "a.http block 2 (login), a.http block 1, b.http block 1 (create), b.http block 2, b.http block 3"
+	if got := planLabels(httpFileContent, plan); got != expected {
+		t.Errorf("Incorrect plan. expected: %s, Got: %s", expected, got)
+	}
+	// b.http block 1 depends on login through its Authorization header
+	if fmt.Sprint(plan[2].dependsOn) != "[0]" || fmt.Sprint(plan[2].after) != "[0 1]" {
+		t.Errorf("Incorrect dependencies of create: %v, after %v", plan[2].dependsOn, plan[2].after)
+	}
+
+	// Files aren't chained to each other when they run in parallel
+	plan, _ = planBlocks(httpFileContent, nil, false)
+	if fmt.Sprint(plan[2].after) != "[0]" || fmt.Sprint(plan[3].after) != "[2]" || fmt.Sprint(plan[4].after) != "[3]" {
+		t.Errorf("Incorrect parallel plan: %+v", plan)
+	}
+
+	plan, err = planBlocks(httpFileContent, []string{"b.http:2"}, true)
+	if err != nil {
+		t.Fatalf("got error on function planBlocks: %v", err)
+	}
+	expected = "a.http block 2 (login), b.http block 1 (create), b.http block 2"
+	if got := planLabels(httpFileContent, plan); got != expected {
+		t.Errorf("Incorrect --only plan. expected: %s, Got: %s", expected, got)
+	}
+
+	if _, err := planBlocks(httpFileContent, []string{"missing"}, true); err == nil {
+		t.Errorf("expected an error for an unknown --only block")
+	}
+}
+
+func TestPlanBlocksErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			"cycle",
+			map[string]string{
+				"a.http": "// @name login\n// @depends-on create\nPOST http://localhost/login HTTP/1.1",
+				"b.http": "GET http://localhost/health HTTP/1.1\n###\n// @name create\nPOST http://localhost/users/{{login.response.body.$.id}} HTTP/1.1",
+			},
+			"dependency cycle: a.http block 1 (login) -> b.http block 2 (create) -> a.http block 1 (login)",
+		},
+		{
+			"unknown block",
+			map[string]string{"a.http": "// @depends-on login\nGET http://localhost/me HTTP/1.1"},
+			"a.http block 1: @depends-on login, no block has // @name login",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := writeHTTPFiles(t, &Config{}, tc.files)
+			if err == nil || !strings.HasSuffix(err.Error(), tc.expected) {
+				t.Errorf("expected error %q, Got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestSendRequestsSkipsDependants(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	files := map[string]string{
+		"a.http": fmt.Sprintf("// @name login\n// @assert status == 200\nPOST %[1]s/login HTTP/1.1\n###\n// @name me\n// @depends-on login\nGET %[1]s/me HTTP/1.1\n###\n// @depends-on me\nGET %[1]s/me/orders HTTP/1.1\n###\nGET %[1]s/health HTTP/1.1", server.URL),
+	}
+	for _, parallel := range []int{1, 4} {
+		config := &Config{HTTPRequestTimeout: 2000, Parallel: parallel}
+		httpFileContent, err := writeHTTPFiles(t, config, files)
+		if err != nil {
+			t.Fatalf("got error on function processHTTPFiles: %v", err)
+		}
+		summary := sendRequests(context.Background(), httpFileContent, config)
+		summary.Duration = 0
+		if summary != (runSummary{Passed: 1, Failed: 1, Skipped: 2}) {
+			t.Errorf("Incorrect summary with --parallel %d: %+v", parallel, summary)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffLine is a line of a diff, Kind is ' ' for unchanged lines, '-' for removed and '+' for added ones
+type diffLine struct {
+	Kind byte
+	Text string
+}
+
+// Above this many compared line pairs the changed lines are shown as removed then added,
+// instead of looking for the lines they have in common
+const maxDiffCells = 4_000_000
+
+// Lines of context around the changes of a unified diff
+const diffContext = 3
+
+// diffLines returns the lines to remove from a and add to get b, keeping the longest
+// common subsequence of lines unchanged
+func diffLines(a, b []string) []diffLine {
+	// Common prefix and suffix don't need the table below
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{' ', text})
+	}
+
+	x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(x)*len(y) > maxDiffCells {
+		for _, text := range x {
+			lines = append(lines, diffLine{'-', text})
+		}
+		for _, text := range y {
+			lines = append(lines, diffLine{'+', text})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+		lcs := make([][]int, len(x)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(y)+1)
+		}
+		for i := len(x) - 1; i >= 0; i-- {
+			for j := len(y) - 1; j >= 0; j-- {
+				if x[i] == y[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(x) || j < len(y) {
+			switch {
+			case i < len(x) && j < len(y) && x[i] == y[j]:
+				lines = append(lines, diffLine{' ', x[i]})
+				i++
+				j++
+			case j == len(y) || (i < len(x) && lcs[i+1][j] >= lcs[i][j+1]):
+				lines = append(lines, diffLine{'-', x[i]})
+				i++
+			default:
+				lines = append(lines, diffLine{'+', y[j]})
+				j++
+			}
+		}
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	return lines
+}
+
+// unifiedDiff returns the unified diff between two texts, nothing when they are the same
+func unifiedDiff(fromName, toName, from, to string) []string {
+	lines := diffLines(strings.Split(from, "\n"), strings.Split(to, "\n"))
+
+	var changes []int
+	for i, line := range lines {
+		if line.Kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	diff := []string{"--- " + fromName, "+++ " + toName}
+	for c := 0; c < len(changes); {
+		// A hunk goes on while the next change is close enough to share its context
+		start := max(changes[c]-diffContext, 0)
+		end := changes[c]
+		for c < len(changes) && changes[c] <= end+2*diffContext {
+			end = changes[c]
+			c++
+		}
+		end = min(end+diffContext, len(lines)-1)
+
+		// Line numbers of the hunk in both texts
+		fromLine, toLine := 1, 1
+		for _, line := range lines[:start] {
+			if line.Kind != '+' {
+				fromLine++
+			}
+			if line.Kind != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, line := range lines[start : end+1] {
+			if line.Kind != '+' {
+				fromCount++
+			}
+			if line.Kind != '-' {
+				toCount++
+			}
+		}
+
+		diff = append(diff, fmt.Sprintf("@@ -%d,%d +%d,%d @@", fromLine, fromCount, toLine, toCount))
+		for _, line := range lines[start : end+1] {
+			diff = append(diff, string(line.Kind)+line.Text)
+		}
+	}
+	return diff
+}
+
+// printDiff prints a unified diff, removed lines in red and added ones in green
+func printDiff(diff []string, indent string) {
+	for _, line := range diff {
+		color := C_Gray
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			color = C_Bold
+		case strings.HasPrefix(line, "@@"):
+			color = C_Cyan
+		case strings.HasPrefix(line, "-"):
+			color = C_Red
+		case strings.HasPrefix(line, "+"):
+			color = C_Green
+		}
+		fmt.Printf("%s%s%s%s\n", indent, color, line, C_Reset)
+	}
+}
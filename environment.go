@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	envFileName        = "http-client.env.json"
+	privateEnvFileName = "http-client.private.env.json"
+	sharedEnvName      = "$shared" // variables available in every environment
+)
+
+// environmentFilePaths returns the environment files that sit next to an .http file
+func environmentFilePaths(httpFilePath string) []string {
+	dir := filepath.Dir(httpFilePath)
+	return []string{
+		filepath.Join(dir, envFileName),
+		filepath.Join(dir, privateEnvFileName),
+	}
+}
+
+// loadEnvironmentVariables reads http-client.env.json and http-client.private.env.json
+// next to every .http file and keeps the variables of the selected environment.
+// From lowest to highest precedence:
+//
+//	$shared in http-client.env.json
+//	$shared in http-client.private.env.json
+//	--env in http-client.env.json
+//	--env in http-client.private.env.json
+//	@name = value lines in the .http file (applied by getGlobalVariables)
+func loadEnvironmentVariables(httpFileContent []HTTPFileContent, config *Config) ([]HTTPFileContent, error) {
+	for i, file := range httpFileContent {
+		variables := make(map[string]string)
+		found := config.Environment == ""
+
+		var environments []map[string]map[string]any
+		for _, path := range environmentFilePaths(file.FilePath) {
+			envs, err := readEnvironmentFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if envs != nil {
+				logVerbose(config, "Loaded environment file %s", path)
+				environments = append(environments, envs)
+			}
+		}
+
+		for _, name := range []string{sharedEnvName, config.Environment} {
+			if name == "" {
+				continue
+			}
+			for _, envs := range environments {
+				values, ok := envs[name]
+				if !ok {
+					continue
+				}
+				if name == config.Environment {
+					found = true
+				}
+				for key, value := range values {
+					variables[key] = formatJSONValue(value)
+				}
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("environment %q not found in %s or %s next to %s", config.Environment, envFileName, privateEnvFileName, file.FilePath)
+		}
+
+		httpFileContent[i].EnvironmentVariables = variables
+	}
+
+	return httpFileContent, nil
+}
+
+// readEnvironmentFile returns nil when the file does not exist
+func readEnvironmentFile(path string) (map[string]map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading environment file %s: %w", path, err)
+	}
+
+	var environments map[string]map[string]any
+	if err := json.Unmarshal(content, &environments); err != nil {
+		return nil, fmt.Errorf("error parsing environment file %s: %w", path, err)
+	}
+	return environments, nil
+}
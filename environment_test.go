@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvironmentVariables(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		envFileName: `{
+			"$shared": {"host": "http://shared", "version": "v1", "token": "shared"},
+			"local": {"host": "http://localhost:8080"},
+			"docker": {"host": "http://api:8080", "port": 8080}
+		}`,
+		privateEnvFileName: `{
+			"$shared": {"token": "private-shared"},
+			"docker": {"token": "private-docker"}
+		}`,
+		"requests.http": "@version = v2\nGET {{host}}/{{version}}/{{token}}/{{port}} HTTP/1.1",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		env      string
+		expected string
+	}{
+		{"", "http://shared/v2/private-shared/{{port}}"},
+		{"local", "http://localhost:8080/v2/private-shared/{{port}}"},
+		{"docker", "http://api:8080/v2/private-docker/8080"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.env, func(t *testing.T) {
+			config := &Config{HTTPFilePath: filepath.Join(dir, "requests.http"), Environment: tc.env}
+			httpFileContent, err := processHTTPFiles(config)
+			if err != nil {
+				t.Fatalf("got error on function processHTTPFiles: %v", err)
+			}
+			got := httpFileContent[0].Blocks[0].Request.Url
+			if got != tc.expected {
+				t.Errorf("Incorrect URL.\nexpected: %s\nGot:      %s", tc.expected, got)
+			}
+		})
+	}
+
+	t.Run("unknown environment", func(t *testing.T) {
+		config := &Config{HTTPFilePath: filepath.Join(dir, "requests.http"), Environment: "staging"}
+		if _, err := processHTTPFiles(config); err == nil {
+			t.Errorf("Should output error for an unknown environment")
+		}
+	})
+}
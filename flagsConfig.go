@@ -13,13 +13,33 @@ type Config struct {
 	WatchFolderPath string // folder to be watch for changes
 	WatchFilePath   string // File to be watched for changes
 	// .http files must be watched for changes as well, and if are changed, the program must be update.
-	HTTPFilePath       string // optional, if no file path is passed, it must search the first one on the same directory program was run.
-	HTTPFolderPath     string // optional, if no folder path is passed, it must search all .http files in the same directory program was run.
-	ExcludeFile        string // this can be an exact folder or a pattern of files, which means this files won't be watched.
-	ExcludeFolder      string // this means any file inside this folder will be ignore or not watched.
-	SleepTime          int    // Time to wait in between the HTTP requests
-	HTTPRequestTimeout int    // Time each request waits before considered failed
-	Verbose            bool   // Detailed Loging for debugging purposes
+	HTTPFilePath       string            // optional, if no file path is passed, it must search the first one on the same directory program was run.
+	HTTPFolderPath     string            // optional, if no folder path is passed, it must search all .http files in the same directory program was run.
+	ExcludeFile        string            // this can be an exact folder or a pattern of files, which means this files won't be watched.
+	ExcludeFolder      string            // this means any file inside this folder will be ignore or not watched.
+	Only               []string          // blocks sent with --only, by `// @name` or file.http:id, with the blocks they depend on
+	Parallel           int               // blocks sent at the same time, files run in parallel
+	SleepTime          int               // Time to wait in between the HTTP requests
+	HTTPRequestTimeout int               // Time each request waits before considered failed
+	Retries            int               // attempts after the first one for transport errors, 502, 503 and 504
+	RetryBackoff       int               // wait before the first retry in milliseconds, doubled for each retry after it
+	WaitFor            string            // URL or host:port polled before each run until the server answers
+	WaitForTimeout     int               // how long --wait-for polls before giving up (milliseconds)
+	WaitForInterval    int               // time between two polls of --wait-for (milliseconds)
+	Environment        string            // environment selected from http-client.env.json
+	PromptValues       map[string]string // answers of `// @prompt` variables given with --prompt name=value
+	SnapshotUpdate     bool              // save the responses as snapshots instead of comparing them
+	Run                bool              // `lazyrequests run`, sends everything once and exits instead of watching
+	Yes                bool              // `// @note` blocks are sent without asking, for runs nobody answers
+	Reporters          []reporterConfig  // where the results go, from --reporter and --report-file
+	ShowBody           bool              // print the response bodies, pretty printed
+	ShowHeaders        bool              // print the response headers
+	ShowBodyLimit      int               // printed bodies are cut after this many bytes, 0 prints them whole
+	Diff               bool              // print how each response changed since the previous run while watching
+	HTMLReport         string            // HTML file with every request and response of a run
+	HistoryFile        string            // JSON lines log of the sent requests, none by default
+	HistoryBodyLimit   int               // bodies are cut at this many bytes in the history, negative keeps them whole
+	Verbose            bool              // Detailed Loging for debugging purposes
 }
 
 func logVerbose(config *Config, format string, args ...any) {
@@ -43,8 +63,18 @@ func flagsConfig() (*Config, error) {
 		HTTPFolderPath:     "",
 		ExcludeFile:        "",
 		ExcludeFolder:      "",
+		Parallel:           1,
 		SleepTime:          100,   // Time in between requsts Default 50 milliseconds for developement
 		HTTPRequestTimeout: 10000, // default 3 seconds
+		RetryBackoff:       200,
+		WaitForTimeout:     30000,
+		WaitForInterval:    500,
+		Environment:        "",
+		PromptValues:       make(map[string]string),
+		SnapshotUpdate:     false,
+		Run:                false,
+		ShowBodyLimit:      4096,
+		HistoryBodyLimit:   64 * 1024,
 		Verbose:            false,
 	}
 
@@ -56,10 +86,46 @@ func flagsConfig() (*Config, error) {
 	flag.StringVar(&config.ExcludeFile, "exclude-file", config.ExcludeFile, "File pattern to exclude from watching")
 	flag.StringVar(&config.ExcludeFolder, "exclude-folder", config.ExcludeFolder, "Folder to exclude from watching")
 	flag.IntVar(&config.SleepTime, "sleep-time", config.SleepTime, "Time to wait between each HTTP requests (milliseconds)")
+	flag.Var((*listFlag)(&config.Only), "only", "Send only these blocks and the ones they depend on, by // @name or file.http:id, comma separated or repeated")
+	flag.IntVar(&config.Parallel, "parallel", config.Parallel, "Blocks sent at the same time, files run in parallel and the blocks of a file in order unless it has // @parallel")
 	flag.IntVar(&config.HTTPRequestTimeout, "time-out", config.HTTPRequestTimeout, "Timeout for each request before failing (milliseconds)")
+	flag.IntVar(&config.Retries, "retries", config.Retries, "Retries of GET, HEAD, OPTIONS, PUT and DELETE requests on transport errors, 502, 503 and 504")
+	flag.IntVar(&config.RetryBackoff, "retry-backoff", config.RetryBackoff, "Wait before the first retry, doubled for each retry after it (milliseconds)")
+	flag.StringVar(&config.WaitFor, "wait-for", config.WaitFor, "URL or host:port polled before each run until the server answers, instead of sleeping")
+	flag.IntVar(&config.WaitForTimeout, "wait-for-timeout", config.WaitForTimeout, "How long --wait-for polls before giving up (milliseconds)")
+	flag.IntVar(&config.WaitForInterval, "wait-for-interval", config.WaitForInterval, "Time between two polls of --wait-for (milliseconds)")
+	flag.StringVar(&config.Environment, "env", config.Environment, "Environment from http-client.env.json to use")
+	flag.Var(promptFlag(config.PromptValues), "prompt", "Value of a `// @prompt` variable as name=value, can be repeated")
+	flag.BoolVar(&config.Yes, "yes", config.Yes, "Send the // @note blocks of a run without asking for confirmation")
+	flag.BoolVar(&config.SnapshotUpdate, "snapshot-update", config.SnapshotUpdate, "Save the responses in __snapshots__ instead of comparing them")
+	var reporterNames listFlag
+	var reportFile string
+	flag.Var(&reporterNames, "reporter", "Reporters of the results: terminal, junit, tap or json, comma separated or repeated, name=file writes to a file")
+	flag.StringVar(&reportFile, "report-file", "", "File written by the junit, tap or json reporter")
+	flag.BoolVar(&config.ShowBody, "show-body", config.ShowBody, "Print the response bodies, JSON, XML and HTML pretty printed")
+	flag.BoolVar(&config.ShowHeaders, "show-headers", config.ShowHeaders, "Print the response headers")
+	flag.IntVar(&config.ShowBodyLimit, "show-body-limit", config.ShowBodyLimit, "Printed bodies are cut after this many bytes, 0 prints them whole")
+	flag.BoolVar(&config.Diff, "diff", false, "Print how each response changed since the previous run")
+	flag.StringVar(&config.HTMLReport, "html-report", "", "HTML file with every request and response of the run")
+	flag.StringVar(&config.HistoryFile, "history-file", config.HistoryFile, "File where every sent request and its response is logged, like requests.jsonl")
+	flag.IntVar(&config.HistoryBodyLimit, "history-body-limit", config.HistoryBodyLimit, "Bodies longer than this are truncated in the history (bytes), -1 keeps them whole")
 	flag.BoolVar(&config.Verbose, "verbose", config.Verbose, "Enable verbose logging")
 
-	flag.Parse()
+	// `lazyrequests run [options]` sends everything once, for CI
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "run" {
+		config.Run = true
+		args = args[1:]
+	}
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return nil, err
+	}
+
+	reporters, err := parseReporters(reporterNames, reportFile)
+	if err != nil {
+		return nil, err
+	}
+	config.Reporters = reporters
 
 	if config.Verbose {
 		flag.Visit(func(f *flag.Flag) {
@@ -68,7 +134,24 @@ func flagsConfig() (*Config, error) {
 	}
 
 	// Validate configuration
-	if config.WatchFolderPath == "" && config.WatchFilePath == "" {
+	if config.Parallel < 1 {
+		return nil, fmt.Errorf("--parallel must be at least 1")
+	}
+	if config.Retries < 0 || config.RetryBackoff < 0 {
+		return nil, fmt.Errorf("--retries and --retry-backoff cannot be negative")
+	}
+	if config.WaitFor != "" {
+		if _, _, err := parseWaitTarget(config.WaitFor); err != nil {
+			return nil, err
+		}
+		if config.WaitForTimeout <= 0 || config.WaitForInterval <= 0 {
+			return nil, fmt.Errorf("--wait-for-timeout and --wait-for-interval must be positive")
+		}
+	}
+	if config.Yes && !config.Run {
+		return nil, fmt.Errorf("--yes only makes sense with `lazyrequests run`")
+	}
+	if !config.Run && config.WatchFolderPath == "" && config.WatchFilePath == "" {
 		return nil, fmt.Errorf("either --watch-folder or --watch-file must be specified")
 	}
 	fmt.Println(config.ExcludeFile)
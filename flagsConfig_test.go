@@ -19,6 +19,10 @@ func TestParseConfig_Errors(t *testing.T) {
 		{"non existing folder", []string{"main", "--watch-folder", "./nonexisting/"}},
 		{"non existing file", []string{"main", "--watch-file", "./nonexisting/file.http"}},
 		{"file as folder", []string{"main", "--watch-folder", "./main.go"}},
+		{"no parallel workers", []string{"main", "run", "--parallel", "0"}},
+		{"negative retries", []string{"main", "run", "--retries", "-1"}},
+		{"invalid wait-for", []string{"main", "run", "--wait-for", "ftp://localhost"}},
+		{"yes while watching", []string{"main", "--watch-folder", "./http_folder/", "--yes"}},
 	}
 
 	for _, tc := range tests {
@@ -36,3 +40,15 @@ func TestParseConfig_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestParseConfig_Run(t *testing.T) {
+	resetFlags()
+	os.Args = []string{"main", "run", "--http-file", "./http_folder/test_0_bare.http", "--sleep-time", "0"}
+	config, err := flagsConfig()
+	if err != nil {
+		t.Fatalf("run mode shouldn't need --watch-folder or --watch-file: %v", err)
+	}
+	if !config.Run || config.HTTPFilePath != "./http_folder/test_0_bare.http" || config.SleepTime != 0 {
+		t.Errorf("Incorrect config: %+v", config)
+	}
+}
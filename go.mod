@@ -3,10 +3,19 @@ module lazyrequests
 go 1.23.5
 
 require (
-	github.com/fsnotify/fsnotify v1.8.0 // indirect
+	github.com/andybalholm/brotli v1.2.0
+	github.com/dop251/goja v0.0.0-20260106131823-651366fbe6e3
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
+	golang.org/x/text v0.19.0
+)
+
+require (
+	github.com/dlclark/regexp2 v1.11.4 // indirect
+	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
+	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
 	github.com/k0kubun/pp/v3 v3.4.1 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.16 // indirect
 	golang.org/x/sys v0.13.0 // indirect
-	golang.org/x/text v0.19.0 // indirect
 )
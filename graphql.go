@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Header that marks a block as a GraphQL request, it's not sent to the server
+const graphQLRequestTypeHeader = "X-Request-Type"
+
+// buildGraphQLBody wraps a GraphQL block body, a query optionally followed by a
+// blank line and a JSON object of variables, into {"query": ..., "variables": ...}
+func buildGraphQLBody(body string) ([]byte, error) {
+	query := strings.TrimSpace(body)
+	var variables json.RawMessage
+
+	// The variables are the last paragraph, when it is a JSON object.
+	// A selection set like `{ user { id } }` is not valid JSON so it stays in the query.
+	if index := strings.LastIndex(query, "\n\n"); index != -1 {
+		candidate := strings.TrimSpace(query[index:])
+		if strings.HasPrefix(candidate, "{") && json.Valid([]byte(candidate)) {
+			variables = json.RawMessage(candidate)
+			query = strings.TrimSpace(query[:index])
+		}
+	}
+	if query == "" {
+		return nil, fmt.Errorf("empty GraphQL query")
+	}
+
+	return json.Marshal(struct {
+		Query     string          `json:"query"`
+		Variables json.RawMessage `json:"variables,omitempty"`
+	}{
+		Query:     query,
+		Variables: variables,
+	})
+}
+
+// graphQLErrors returns the messages of the `errors` array of a GraphQL response,
+// servers answer 200 even when the query failed
+func graphQLErrors(body []byte) []string {
+	data, err := decodeJSON(body)
+	if err != nil {
+		return nil
+	}
+	object, ok := data.(map[string]any)
+	if !ok {
+		return nil
+	}
+	errs, ok := object["errors"].([]any)
+	if !ok {
+		return nil
+	}
+
+	var messages []string
+	for _, e := range errs {
+		if m, ok := e.(map[string]any); ok {
+			if message, ok := m["message"].(string); ok {
+				messages = append(messages, message)
+				continue
+			}
+		}
+		messages = append(messages, formatJSONValue(e))
+	}
+	return messages
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBuildGraphQLBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"query only", "query { users { id } }\n\n", `{"query":"query { users { id } }"}`},
+		{
+			"query and variables",
+			"query User($id: ID!) {\n  user(id: $id) { name }\n}\n\n{\"id\": \"7\"}\n\n",
+			`{"query":"query User($id: ID!) {\n  user(id: $id) { name }\n}","variables":{"id":"7"}}`,
+		},
+		{"selection set after blank line", "query {\n\n{ user { id } }\n}", `{"query":"query {\n\n{ user { id } }\n}"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := buildGraphQLBody(tc.body)
+			if err != nil {
+				t.Fatalf("got error on function buildGraphQLBody: %v", err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("Incorrect body.\nexpected: %s\nGot:      %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseGraphQLRequest(t *testing.T) {
+	req, err := stringToHTTPStruct("POST http://localhost:8080/graphql HTTP/1.1\nX-REQUEST-TYPE: GraphQL\n\nquery { me { id } }", "requests.http")
+	if err != nil {
+		t.Fatalf("got error on function stringToHTTPStruct: %v", err)
+	}
+	if !req.GraphQL {
+		t.Errorf("Should be a GraphQL request")
+	}
+	if headerValue(req.Headers, graphQLRequestTypeHeader) != "" {
+		t.Errorf("X-Request-Type should not be sent: %v", req.Headers)
+	}
+	if req.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Incorrect Content-Type: %s", req.Headers["Content-Type"])
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		errors int
+	}{
+		{"data", `{"data": {"me": {"id": 1}}}`, 0},
+		{"empty errors", `{"data": null, "errors": []}`, 0},
+		{"errors", `{"errors": [{"message": "not allowed"}, {"message": "bad id"}]}`, 2},
+		{"not JSON", `<html></html>`, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := graphQLErrors([]byte(tc.body)); len(got) != tc.errors {
+				t.Errorf("expected %d errors, Got: %v", tc.errors, got)
+			}
+		})
+	}
+}
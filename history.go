@@ -0,0 +1,493 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// History file read by `lazyrequests history` without --history-file, relative to where it runs
+const defaultHistoryFile = "requests.jsonl"
+
+// Environment variables with the value of a redacted header on replay, like LAZYREQUESTS_HEADER_AUTHORIZATION
+const headerEnvPrefix = "LAZYREQUESTS_HEADER_"
+
+// historyEntry is a line of the history: a request sent by a block and what came back
+type historyEntry struct {
+	ID              int         `json:"-"` // line number in the history file
+	Run             int         `json:"run"`
+	Time            time.Time   `json:"time"`
+	File            string      `json:"file"`
+	Block           int         `json:"block"`
+	Name            string      `json:"name"`
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"requestHeaders,omitempty"`
+	RequestBody     historyBody `json:"requestBody"`
+	Status          string      `json:"status,omitempty"`
+	StatusCode      int         `json:"statusCode,omitempty"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	ResponseBody    historyBody `json:"responseBody"`
+	DurationMs      int64       `json:"durationMs"`
+	Outcome         string      `json:"outcome"`
+	Error           string      `json:"error,omitempty"`
+	Failures        []string    `json:"failures,omitempty"`
+	// Sensitive headers and secret prompt answers are hidden, replay asks for the request headers again
+	RedactedHeaders  []string `json:"redactedHeaders,omitempty"`  // request headers whose values were hidden
+	RequestRedacted  bool     `json:"requestRedacted,omitempty"`  // a secret was hidden in the URL or the request body
+	ResponseRedacted bool     `json:"responseRedacted,omitempty"` // response headers or body, it doesn't matter for replay
+}
+
+// historyBody is a body cut at --history-body-limit, binary ones are base64
+type historyBody struct {
+	Text      string `json:"text,omitempty"`
+	Base64    bool   `json:"base64,omitempty"`
+	File      string `json:"file,omitempty"` // streamed `< ./file` request bodies
+	Size      int    `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+func newHistoryBody(body []byte, limit int) historyBody {
+	recorded := historyBody{Size: len(body)}
+	if limit >= 0 && len(body) > limit {
+		body = body[:limit]
+		recorded.Truncated = true
+	}
+	if utf8.Valid(body) {
+		recorded.Text = string(body)
+	} else {
+		recorded.Text = base64.StdEncoding.EncodeToString(body)
+		recorded.Base64 = true
+	}
+	return recorded
+}
+
+func (b historyBody) bytes() ([]byte, error) {
+	if b.Base64 {
+		return base64.StdEncoding.DecodeString(b.Text)
+	}
+	return []byte(b.Text), nil
+}
+
+func newHistoryEntry(run int, result blockResult, bodyLimit int) historyEntry {
+	entry := historyEntry{
+		Run:             run,
+		Time:            time.Now(),
+		File:            result.File,
+		Block:           result.Block.ID,
+		Name:            result.name(),
+		Method:          result.Method,
+		URL:             result.URL,
+		RequestHeaders:  result.RequestHeaders,
+		RequestBody:     newHistoryBody([]byte(result.RequestBody), bodyLimit),
+		Status:          result.Status,
+		ResponseHeaders: result.ResponseHeaders,
+		ResponseBody:    newHistoryBody(result.ResponseBody, bodyLimit),
+		DurationMs:      result.Duration.Milliseconds(),
+		Outcome:         result.Outcome,
+		Failures:        result.messages(),
+	}
+	if result.RequestBodyFile != "" {
+		entry.RequestBody = historyBody{File: result.RequestBodyFile}
+	}
+	if result.Err != nil {
+		entry.Error = result.Err.Error()
+	}
+	entry.StatusCode, _ = strconv.Atoi(strings.SplitN(result.Status, " ", 2)[0])
+	redactHistoryEntry(&entry, result.Secrets)
+	return entry
+}
+
+// redactHistoryEntry hides the sensitive headers and the answers of secret prompts, like
+// `// @prompt password`, wherever they were sent
+func redactHistoryEntry(entry *historyEntry, secrets []string) {
+	redact := func(text string, redacted *bool) string {
+		text, found := redactSecrets(text, secrets)
+		*redacted = *redacted || found
+		return text
+	}
+
+	// Only printed, nothing of them is sent again
+	entry.Name, _ = redactSecrets(entry.Name, secrets)
+	entry.Error, _ = redactSecrets(entry.Error, secrets)
+	for i, failure := range entry.Failures {
+		entry.Failures[i], _ = redactSecrets(failure, secrets)
+	}
+
+	entry.URL = redact(entry.URL, &entry.RequestRedacted)
+	if !entry.RequestBody.Base64 {
+		entry.RequestBody.Text = redact(entry.RequestBody.Text, &entry.RequestRedacted)
+	}
+	entry.RequestHeaders, entry.RedactedHeaders = redactHeaders(entry.RequestHeaders, secrets)
+
+	var responseHeaders []string
+	entry.ResponseHeaders, responseHeaders = redactHeaders(entry.ResponseHeaders, secrets)
+	entry.ResponseRedacted = len(responseHeaders) > 0
+	if !entry.ResponseBody.Base64 {
+		entry.ResponseBody.Text = redact(entry.ResponseBody.Text, &entry.ResponseRedacted)
+	}
+}
+
+// historyReporter appends every sent block to the history file, line by line so a
+// run that is interrupted keeps what was already sent
+type historyReporter struct {
+	path      string
+	bodyLimit int
+	run       int
+	file      *os.File
+	err       error
+}
+
+func (h *historyReporter) runStarted(run int, start time.Time) {
+	h.run, h.err = run, nil
+	if dir := filepath.Dir(h.path); dir != "" {
+		if h.err = os.MkdirAll(dir, 0o755); h.err != nil {
+			return
+		}
+	}
+	h.file, h.err = os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+}
+
+func (h *historyReporter) blockFinished(result blockResult) {
+	if h.file == nil || h.err != nil || result.Outcome == outcomeSkipped {
+		return
+	}
+	line, err := json.Marshal(newHistoryEntry(h.run, result, h.bodyLimit))
+	if err != nil {
+		h.err = err
+		return
+	}
+	_, h.err = h.file.Write(append(line, '\n'))
+}
+
+func (h *historyReporter) runFinished(summary runSummary, results []blockResult) error {
+	if h.file != nil {
+		if err := h.file.Close(); h.err == nil {
+			h.err = err
+		}
+		h.file = nil
+	}
+	if h.err != nil {
+		return fmt.Errorf("history %s: %w", h.path, h.err)
+	}
+	return nil
+}
+
+// readHistory reads the entries of a history file, lines that aren't requests are skipped
+func readHistory(path string) ([]historyEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Method == "" {
+			continue
+		}
+		entry.ID = lineNumber
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// historyFilter is what `lazyrequests history list` keeps
+type historyFilter struct {
+	Status  string // 404, 4xx or error for the requests without response
+	URL     string // part of the URL
+	Outcome string
+	Run     int
+}
+
+func (f historyFilter) match(entry historyEntry) bool {
+	if f.URL != "" && !strings.Contains(entry.URL, f.URL) {
+		return false
+	}
+	if f.Outcome != "" && entry.Outcome != f.Outcome {
+		return false
+	}
+	if f.Run != 0 && entry.Run != f.Run {
+		return false
+	}
+	switch status := strings.ToLower(f.Status); {
+	case status == "":
+		return true
+	case status == "error":
+		return entry.StatusCode == 0
+	case len(status) == 3 && strings.HasSuffix(status, "xx"):
+		return entry.StatusCode/100 == int(status[0]-'0')
+	default:
+		return strconv.Itoa(entry.StatusCode) == status
+	}
+}
+
+// historyCommand is `lazyrequests history`, returns the exit code:
+//
+//	history [list] [--status 4xx] [--url /users] [--outcome failed] [--run 3]
+//	history show <id>
+//	history replay <id> [--header "Authorization: Bearer ..."]
+func historyCommand(args []string) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	path := flags.String("history-file", defaultHistoryFile, "History file to read")
+	var filter historyFilter
+	flags.StringVar(&filter.Status, "status", "", "Only the responses with this status: 404, 4xx or error")
+	flags.StringVar(&filter.URL, "url", "", "Only the requests whose URL contains this")
+	flags.StringVar(&filter.Outcome, "outcome", "", "Only the blocks that passed, failed or errored")
+	flags.IntVar(&filter.Run, "run", 0, "Only the requests of this run")
+	timeout := flags.Int("time-out", 10000, "Timeout of a replayed request (milliseconds)")
+	headers := make(headerFlag)
+	flags.Var(headers, "header", "Value of a header redacted in the history as \"Name: value\", can be repeated")
+
+	subcommand := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		subcommand, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	entries, err := readHistory(*path)
+	if err != nil {
+		fmt.Printf("%s%v%s\n", C_Red, err, C_Reset)
+		return exitError
+	}
+
+	switch subcommand {
+	case "list":
+		for _, entry := range entries {
+			if filter.match(entry) {
+				printHistoryEntry(entry)
+			}
+		}
+		return exitPassed
+	case "show", "replay":
+		entry, err := findHistoryEntry(entries, flags.Args())
+		if err != nil {
+			fmt.Printf("%s%v%s\n", C_Red, err, C_Reset)
+			return exitError
+		}
+		if subcommand == "show" {
+			printHistoryExchange(entry)
+			return exitPassed
+		}
+		if err := replayHistoryEntry(entry, headers, time.Duration(*timeout)*time.Millisecond, os.Stdout); err != nil {
+			fmt.Printf("%s%v%s\n", C_Red, err, C_Reset)
+			return exitFailed
+		}
+		return exitPassed
+	default:
+		fmt.Printf("%sunknown history command %q, try: list, show <id>, replay <id>%s\n", C_Yellow, subcommand, C_Reset)
+		return exitError
+	}
+}
+
+func findHistoryEntry(entries []historyEntry, args []string) (historyEntry, error) {
+	if len(args) != 1 {
+		return historyEntry{}, fmt.Errorf("give the id of an entry, see `lazyrequests history list`")
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		return historyEntry{}, fmt.Errorf("invalid id %q", args[0])
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return historyEntry{}, fmt.Errorf("no entry #%d in the history", id)
+}
+
+func outcomeColor(outcome string) string {
+	switch outcome {
+	case outcomePassed:
+		return C_Green
+	case outcomeFailed:
+		return C_Red
+	default:
+		return C_Yellow
+	}
+}
+
+func printHistoryEntry(entry historyEntry) {
+	status := entry.Status
+	if status == "" {
+		status = "error"
+	}
+	fmt.Printf("%s#%-5d%s run %-4d %s %s%-6s %s%-12s %s%5dms %s%s %s(%s block %d)%s\n",
+		C_Gray, entry.ID, C_Reset, entry.Run, entry.Time.Local().Format("2006-01-02 15:04:05"),
+		C_Bold+C_Blue, entry.Method, C_Reset+outcomeColor(entry.Outcome), status,
+		C_Yellow, entry.DurationMs, C_Reset, entry.URL, C_Gray, filepath.Base(entry.File), entry.Block, C_Reset)
+}
+
+// printHistoryExchange prints the whole request and response of an entry
+func printHistoryExchange(entry historyEntry) {
+	fmt.Printf("%s#%d run %d %s %s block %d%s\n", C_Gray, entry.ID, entry.Run, entry.Time.Local().Format("2006-01-02 15:04:05"), entry.File, entry.Block, C_Reset)
+	fmt.Printf("%s%s%s\n\n", C_Purple, entry.Name, C_Reset)
+
+	fmt.Printf("%s%s%s %s\n", C_Bold+C_Blue, entry.Method, C_Reset, entry.URL)
+	printHistoryHeaders(entry.RequestHeaders)
+	printHistoryBody(entry.RequestBody)
+
+	fmt.Println()
+	if entry.Status == "" {
+		fmt.Printf("%s%s%s\n", C_Red, entry.Error, C_Reset)
+	} else {
+		fmt.Printf("%s%s%s %s%dms%s\n", outcomeColor(entry.Outcome), entry.Status, C_Reset, C_Yellow, entry.DurationMs, C_Reset)
+		printHistoryHeaders(entry.ResponseHeaders)
+		printHistoryBody(entry.ResponseBody)
+		for _, failure := range entry.Failures {
+			fmt.Printf("%s%s%s\n", C_Red, failure, C_Reset)
+		}
+	}
+}
+
+func printHistoryHeaders(headers http.Header) {
+	for _, header := range htmlHeaders(headers) {
+		fmt.Printf("%s%s:%s %s\n", C_Cyan, header.Name, C_Reset, header.Value)
+	}
+}
+
+func printHistoryBody(body historyBody) {
+	switch {
+	case body.File != "":
+		fmt.Printf("\n< %s\n", body.File)
+	case body.Base64:
+		fmt.Printf("\n%s<%d bytes of binary data>%s\n", C_Gray, body.Size, C_Reset)
+	case body.Text != "":
+		fmt.Printf("\n%s\n", body.Text)
+	}
+	if body.Truncated {
+		fmt.Printf("%s... truncated, %d bytes in total%s\n", C_Gray, body.Size, C_Reset)
+	}
+}
+
+// headerFlag collects the repeated `--header "Name: value"` flags of replay
+type headerFlag map[string]string
+
+func (h headerFlag) String() string {
+	var headers []string
+	for name, value := range h {
+		headers = append(headers, name+": "+value)
+	}
+	return strings.Join(headers, ", ")
+}
+
+func (h headerFlag) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header must be \"Name: value\": %s", value)
+	}
+	h[http.CanonicalHeaderKey(strings.TrimSpace(name))] = strings.TrimSpace(headerValue)
+	return nil
+}
+
+// redactedHeaderValues gets the values of the request headers redacted in the history: from
+// --header, from LAZYREQUESTS_HEADER_<NAME> or typed in the terminal
+func redactedHeaderValues(entry historyEntry, given map[string]string) (http.Header, error) {
+	values := make(http.Header)
+	var missing []string
+	for _, name := range entry.RedactedHeaders {
+		value, ok := given[http.CanonicalHeaderKey(name)]
+		if !ok {
+			value, ok = os.LookupEnv(headerEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_")))
+		}
+		if !ok && stdinIsTerminal() {
+			setTerminalEcho(false)
+			answer, err := terminal.ask(context.Background(), fmt.Sprintf("%s%s of #%d: %s", C_Yellow, name, entry.ID, C_Reset))
+			setTerminalEcho(true)
+			fmt.Println()
+			value, ok = answer, err == nil
+		}
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		values[name] = []string{value}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("#%d was recorded without the value of %s, pass --header \"%s: value\" or set %s%s",
+			entry.ID, strings.Join(missing, ", "), missing[0], headerEnvPrefix, strings.ToUpper(strings.ReplaceAll(missing[0], "-", "_")))
+	}
+	return values, nil
+}
+
+// replayHistoryEntry sends a recorded request again, with the same method, URL, headers and body.
+// The values of redacted headers come from headers or redactedHeaderValues.
+func replayHistoryEntry(entry historyEntry, headers map[string]string, timeout time.Duration, out io.Writer) error {
+	if entry.RequestBody.Truncated {
+		return fmt.Errorf("the body of #%d was truncated when recorded, it can't be replayed exactly, raise --history-body-limit", entry.ID)
+	}
+	if entry.RequestRedacted {
+		return fmt.Errorf("a secret prompt answer was redacted from the URL or body of #%d, send it again from %s", entry.ID, entry.File)
+	}
+	redactedHeaders, err := redactedHeaderValues(entry, headers)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if entry.RequestBody.File != "" {
+		file, err := os.Open(entry.RequestBody.File)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		body = file
+	} else {
+		data, err := entry.RequestBody.bytes()
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, entry.Method, entry.URL, body)
+	if err != nil {
+		return err
+	}
+	for name, values := range entry.RequestHeaders {
+		req.Header[name] = values
+	}
+	for name, values := range redactedHeaders {
+		req.Header[name] = values
+	}
+
+	startTime := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	elapsedTime := time.Since(startTime)
+
+	fmt.Fprintf(out, "%s%-6s %s%-12s %s%3dms %s%s%s\n", C_Bold+C_Blue, entry.Method, C_Reset+C_Green, resp.Status, C_Yellow, elapsedTime.Milliseconds(), C_Gray, entry.URL, C_Reset)
+	if entry.Status != "" && entry.Status != resp.Status {
+		fmt.Fprintf(out, "%srecorded %s%s\n", C_Yellow, entry.Status, C_Reset)
+	}
+	if len(responseBody) > 0 {
+		fmt.Fprintf(out, "%s\n", reportBody(responseBody))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, fmt.Sprintf("%s %s %s %s", r.Method, r.URL.Path, r.Header.Get("X-Tenant"), body))
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": 1, "name": "a very long name"}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.http")
+	content := "POST %[1]s/users HTTP/1.1\nX-Tenant: acme\n\n{\"name\": \"bob\"}\n###\nGET %[1]s/missing HTTP/1.1\n###\nGET http://127.0.0.1:1/down HTTP/1.1"
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(content, server.URL)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	historyFile := filepath.Join(dir, "history", "requests.jsonl")
+	config := &Config{HTTPFilePath: path, HTTPRequestTimeout: 2000, HistoryFile: historyFile, HistoryBodyLimit: 20}
+	httpFileContent, err := processHTTPFiles(config)
+	if err != nil {
+		t.Fatalf("got error on function processHTTPFiles: %v", err)
+	}
+	sendRequests(context.Background(), httpFileContent, config)
+
+	entries, err := readHistory(historyFile)
+	if err != nil {
+		t.Fatalf("got error on function readHistory: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, Got: %d", len(entries))
+	}
+
+	created := entries[0]
+	if created.ID != 1 || created.Block != 1 || created.Method != "POST" || created.StatusCode != 200 || created.Outcome != outcomePassed {
+		t.Errorf("Incorrect entry: %+v", created)
+	}
+	if created.RequestHeaders.Get("X-Tenant") != "acme" || strings.TrimSpace(created.RequestBody.Text) != `{"name": "bob"}` || created.RequestBody.Truncated {
+		t.Errorf("Incorrect recorded request: %+v %+v", created.RequestHeaders, created.RequestBody)
+	}
+	if created.ResponseBody.Text != `{"id": 1, "name": "a` || !created.ResponseBody.Truncated || created.ResponseBody.Size != 37 {
+		t.Errorf("Incorrect truncated response body: %+v", created.ResponseBody)
+	}
+	if entries[2].Status != "" || entries[2].Outcome != outcomeErrored || entries[2].Error == "" {
+		t.Errorf("Incorrect errored entry: %+v", entries[2])
+	}
+
+	filters := []struct {
+		filter   historyFilter
+		expected []int
+	}{
+		{historyFilter{}, []int{1, 2, 3}},
+		{historyFilter{Status: "4xx"}, []int{2}},
+		{historyFilter{Status: "200"}, []int{1}},
+		{historyFilter{Status: "error"}, []int{3}},
+		{historyFilter{URL: "/users"}, []int{1}},
+		{historyFilter{Outcome: outcomeErrored}, []int{3}},
+		{historyFilter{Run: 2}, nil},
+	}
+	for _, tc := range filters {
+		var ids []int
+		for _, entry := range entries {
+			if tc.filter.match(entry) {
+				ids = append(ids, entry.ID)
+			}
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tc.expected) {
+			t.Errorf("Incorrect entries for %+v. expected: %v, Got: %v", tc.filter, tc.expected, ids)
+		}
+	}
+
+	received = nil
+	var out bytes.Buffer
+	if err := replayHistoryEntry(created, nil, 2*time.Second, &out); err != nil {
+		t.Fatalf("got error on function replayHistoryEntry: %v", err)
+	}
+	if len(received) != 1 || strings.TrimSpace(received[0]) != `POST /users acme {"name": "bob"}` {
+		t.Errorf("Incorrect replayed request: %v", received)
+	}
+	if !strings.Contains(out.String(), "200 OK") {
+		t.Errorf("Incorrect replay output: %q", out.String())
+	}
+
+	truncated := created
+	truncated.RequestBody.Truncated = true
+	if err := replayHistoryEntry(truncated, nil, 2*time.Second, &out); err == nil {
+		t.Errorf("a truncated body can't be replayed")
+	}
+}
+
+func TestNewHistoryEntryRedacts(t *testing.T) {
+	result := blockResult{
+		File:            "/api/login.http",
+		Method:          "POST",
+		URL:             "http://localhost/login?password=hunter2",
+		RequestHeaders:  http.Header{"Authorization": {"Bearer abc"}, "X-Password": {"hunter2"}, "X-Tenant": {"acme"}},
+		RequestBody:     `{"user": "bob", "password": "hunter2"}`,
+		Status:          "200 OK",
+		ResponseHeaders: http.Header{"Set-Cookie": {"session=xyz"}},
+		ResponseBody:    []byte(`{"ok": true}`),
+		Outcome:         outcomePassed,
+		Secrets:         []string{"hunter2"},
+	}
+
+	entry := newHistoryEntry(1, result, -1)
+	if line, _ := json.Marshal(entry); strings.Contains(string(line), "hunter2") || strings.Contains(string(line), "abc") || strings.Contains(string(line), "xyz") {
+		t.Errorf("secrets were written to the history: %s", line)
+	}
+	if !entry.RequestRedacted || !entry.ResponseRedacted || fmt.Sprint(entry.RedactedHeaders) != "[Authorization X-Password]" ||
+		entry.RequestHeaders.Get("X-Tenant") != "acme" || entry.RequestBody.Text != `{"user": "bob", "password": "[redacted]"}` {
+		t.Errorf("Incorrect redacted entry: %+v", entry)
+	}
+	if result.RequestHeaders.Get("Authorization") != "Bearer abc" {
+		t.Errorf("the headers of the result shouldn't change")
+	}
+	if err := replayHistoryEntry(entry, map[string]string{"Authorization": "Bearer abc"}, time.Second, io.Discard); err == nil {
+		t.Errorf("an entry with a secret in its body can't be replayed")
+	}
+}
+
+func TestReplayRedactedHeaders(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Authorization"))
+		w.Header().Set("Set-Cookie", "session=xyz")
+	}))
+	defer server.Close()
+
+	isTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = isTerminal }()
+
+	entry := newHistoryEntry(1, blockResult{
+		Method:          "GET",
+		URL:             server.URL + "/me",
+		RequestHeaders:  http.Header{"Authorization": {"Bearer abc"}},
+		Status:          "200 OK",
+		ResponseHeaders: http.Header{"Set-Cookie": {"session=xyz"}},
+		Outcome:         outcomePassed,
+	}, -1)
+	if entry.RequestRedacted || !entry.ResponseRedacted || fmt.Sprint(entry.RedactedHeaders) != "[Authorization]" {
+		t.Fatalf("Incorrect redacted entry: %+v", entry)
+	}
+
+	err := replayHistoryEntry(entry, nil, time.Second, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), `--header "Authorization: value"`) {
+		t.Errorf("expected the missing header to be asked for, Got: %v", err)
+	}
+	if err := replayHistoryEntry(entry, map[string]string{"Authorization": "Bearer new"}, time.Second, io.Discard); err != nil {
+		t.Errorf("got error on function replayHistoryEntry: %v", err)
+	}
+	t.Setenv(headerEnvPrefix+"AUTHORIZATION", "Bearer env")
+	if err := replayHistoryEntry(entry, nil, time.Second, io.Discard); err != nil {
+		t.Errorf("got error on function replayHistoryEntry: %v", err)
+	}
+	if fmt.Sprint(received) != "[Bearer new Bearer env]" {
+		t.Errorf("Incorrect replayed Authorization: %v", received)
+	}
+}
+
+func TestNewHistoryBody(t *testing.T) {
+	binary := []byte{0xff, 0x00, 0xfe}
+	body := newHistoryBody(binary, -1)
+	if !body.Base64 || body.Size != 3 || body.Truncated {
+		t.Errorf("Incorrect binary body: %+v", body)
+	}
+	if decoded, err := body.bytes(); err != nil || !bytes.Equal(decoded, binary) {
+		t.Errorf("Incorrect decoded body: %v %v", decoded, err)
+	}
+	if body := newHistoryBody([]byte("hello"), 0); body.Text != "" || !body.Truncated || body.Size != 5 {
+		t.Errorf("Incorrect body with a 0 limit: %+v", body)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Bodies bigger than this are cut in the HTML report
+const maxReportBody = 256 * 1024
+
+type htmlReport struct {
+	Title     string
+	Generated string
+	Summary   runSummary
+	Duration  string
+	Files     []*htmlReportFile
+}
+
+type htmlReportFile struct {
+	Name     string
+	Path     string
+	Failures int // failed and errored blocks
+	Blocks   []htmlReportBlock
+}
+
+type htmlReportBlock struct {
+	Name            string
+	Outcome         string
+	Method          string
+	URL             string
+	Status          string
+	Duration        string
+	Messages        []string
+	Assertions      []assertionResult
+	Warnings        []string
+	SnapshotDiff    string
+	RequestHeaders  []htmlHeader
+	RequestBody     string
+	ResponseHeaders []htmlHeader
+	ResponseBody    string
+}
+
+type htmlHeader struct {
+	Name  string
+	Value string
+}
+
+// writeHTMLReport writes a single HTML file without external assets, blocks are grouped by
+// .http file and the files with failures come first
+func writeHTMLReport(w io.Writer, summary runSummary, results []blockResult) error {
+	report := htmlReport{
+		Title:     "lazyrequests report",
+		Generated: time.Now().Format("2006-01-02 15:04:05"),
+		Summary:   summary,
+		Duration:  summary.Duration.Round(time.Millisecond).String(),
+	}
+
+	files := make(map[string]*htmlReportFile)
+	for _, result := range results {
+		file, ok := files[result.File]
+		if !ok {
+			file = &htmlReportFile{Name: filepath.Base(result.File), Path: result.File}
+			files[result.File] = file
+			report.Files = append(report.Files, file)
+		}
+		if result.Outcome == outcomeFailed || result.Outcome == outcomeErrored {
+			file.Failures++
+		}
+
+		// Like the history, the report leaves out credentials and secret prompt answers
+		redact := func(text string) string {
+			text, _ = redactSecrets(text, result.Secrets)
+			return text
+		}
+		redactAll := func(texts []string) []string {
+			var redacted []string
+			for _, text := range texts {
+				redacted = append(redacted, redact(text))
+			}
+			return redacted
+		}
+		requestHeaders, _ := redactHeaders(result.RequestHeaders, result.Secrets)
+		responseHeaders, _ := redactHeaders(result.ResponseHeaders, result.Secrets)
+
+		block := htmlReportBlock{
+			Name:            redact(result.name()),
+			Outcome:         result.Outcome,
+			Method:          result.Method,
+			URL:             redact(result.URL),
+			Status:          result.Status,
+			Duration:        fmt.Sprintf("%dms", result.Duration.Milliseconds()),
+			Messages:        redactAll(result.messages()),
+			Warnings:        redactAll(result.Warnings),
+			SnapshotDiff:    redact(strings.Join(result.SnapshotDiff, "\n")),
+			RequestHeaders:  htmlHeaders(requestHeaders),
+			RequestBody:     redact(reportBody([]byte(result.RequestBody))),
+			ResponseHeaders: htmlHeaders(responseHeaders),
+			ResponseBody:    redact(reportBody(result.ResponseBody)),
+		}
+		for _, assertion := range result.Assertions {
+			assertion.Got = redact(assertion.Got)
+			block.Assertions = append(block.Assertions, assertion)
+		}
+		if result.RequestBodyFile != "" {
+			block.RequestBody = "< " + result.RequestBodyFile
+		}
+		file.Blocks = append(file.Blocks, block)
+	}
+	sort.SliceStable(report.Files, func(i, j int) bool {
+		return report.Files[i].Failures > 0 && report.Files[j].Failures == 0
+	})
+
+	return htmlReportTemplate.Execute(w, report)
+}
+
+func htmlHeaders(header http.Header) []htmlHeader {
+	var headers []htmlHeader
+	for name, values := range header {
+		headers = append(headers, htmlHeader{Name: name, Value: strings.Join(values, ", ")})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+// reportBody indents JSON bodies, binary ones are only described
+func reportBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if !utf8.Valid(body) {
+		return fmt.Sprintf("<%d bytes of binary data>", len(body))
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err == nil {
+		body = pretty.Bytes()
+	}
+	if len(body) > maxReportBody {
+		return fmt.Sprintf("%s\n... %d more bytes", body[:maxReportBody], len(body)-maxReportBody)
+	}
+	return string(body)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem; color: #1f2328; background: #fff; }
+h1 { font-size: 1.4rem; margin-bottom: .2rem; }
+.meta { color: #59636e; margin-bottom: 1rem; }
+.summary span { display: inline-block; margin-right: 1rem; font-weight: 600; }
+.toolbar { margin: 1rem 0; }
+details { border: 1px solid #d1d9e0; border-radius: 6px; margin: .5rem 0; }
+details > summary { cursor: pointer; padding: .5rem .75rem; list-style-position: inside; }
+.file > summary { font-weight: 600; background: #f6f8fa; }
+.file > .blocks { padding: 0 .75rem; }
+.block > .content { padding: 0 .75rem .75rem; }
+.outcome { display: inline-block; min-width: 4.5rem; font-weight: 600; text-transform: uppercase; font-size: .8rem; }
+.passed .outcome, .count-passed { color: #1a7f37; }
+.failed .outcome, .count-failed { color: #cf222e; }
+.errored .outcome, .count-errored { color: #9a6700; }
+.skipped .outcome, .count-skipped { color: #59636e; }
+.method { font-weight: 600; color: #0969da; }
+.timing { color: #59636e; float: right; }
+h3 { font-size: .9rem; margin: .75rem 0 .25rem; }
+pre { background: #f6f8fa; padding: .5rem; overflow-x: auto; margin: 0; white-space: pre-wrap; word-break: break-all; }
+table { border-collapse: collapse; font-family: ui-monospace, monospace; font-size: .85rem; }
+td { padding: .1rem .75rem .1rem 0; vertical-align: top; }
+td:first-child { font-weight: 600; white-space: nowrap; }
+ul { margin: .25rem 0; padding-left: 1.25rem; font-family: ui-monospace, monospace; font-size: .85rem; }
+.pass { color: #1a7f37; }
+.fail, .message { color: #cf222e; }
+.warning { color: #9a6700; }
+body.failures-only .block.passed, body.failures-only .block.skipped, body.failures-only .file.clean { display: none; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">{{.Generated}} &middot; {{.Duration}}</div>
+<div class="summary">
+<span class="count-passed">{{.Summary.Passed}} passed</span>
+<span class="count-failed">{{.Summary.Failed}} failed</span>
+<span class="count-errored">{{.Summary.Errored}} errored</span>
+<span class="count-skipped">{{.Summary.Skipped}} skipped</span>
+</div>
+<div class="toolbar"><label><input type="checkbox" id="failures-only"> Only failures</label></div>
+{{range .Files}}
+<details class="file{{if not .Failures}} clean{{end}}" open>
+<summary>{{.Name}} <span class="timing">{{.Path}}</span></summary>
+<div class="blocks">
+{{range .Blocks}}
+<details class="block {{.Outcome}}"{{if or (eq .Outcome "failed") (eq .Outcome "errored")}} open{{end}}>
+<summary><span class="outcome">{{.Outcome}}</span> {{.Name}} <span class="timing">{{.Status}} {{.Duration}}</span></summary>
+<div class="content">
+{{if .Messages}}<ul>{{range .Messages}}<li class="message">{{.}}</li>{{end}}</ul>{{end}}
+{{if .Warnings}}<ul>{{range .Warnings}}<li class="warning">{{.}}</li>{{end}}</ul>{{end}}
+{{if .Assertions}}<h3>Assertions</h3>
+<ul>{{range .Assertions}}{{if .Passed}}<li class="pass">&check; {{.Name}}</li>{{else}}<li class="fail">&cross; {{.Name}} Got: [ {{.Got}} ]</li>{{end}}{{end}}</ul>{{end}}
+{{if .SnapshotDiff}}<h3>Snapshot diff</h3>
+<pre>{{.SnapshotDiff}}</pre>{{end}}
+<h3>Request</h3>
+<pre><span class="method">{{.Method}}</span> {{.URL}}</pre>
+{{if .RequestHeaders}}<table>{{range .RequestHeaders}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>{{end}}</table>{{end}}
+{{if .RequestBody}}<pre>{{.RequestBody}}</pre>{{end}}
+{{if .Status}}<h3>Response</h3>
+<pre>{{.Status}}</pre>
+{{if .ResponseHeaders}}<table>{{range .ResponseHeaders}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>{{end}}</table>{{end}}
+{{if .ResponseBody}}<pre>{{.ResponseBody}}</pre>{{end}}{{end}}
+</div>
+</details>
+{{end}}
+</div>
+</details>
+{{end}}
+<script>
+var failuresOnly = document.getElementById("failures-only");
+failuresOnly.checked = {{if or .Summary.Failed .Summary.Errored}}true{{else}}false{{end}};
+function applyFilter() { document.body.classList.toggle("failures-only", failuresOnly.checked); }
+failuresOnly.addEventListener("change", applyFilter);
+applyFilter();
+</script>
+</body>
+</html>
+`))
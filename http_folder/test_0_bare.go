@@ -4,7 +4,13 @@ type RequestInfo struct {
 	Url               string
 	Method            string
 	CommentIdentifier string
+	Name              string
 	Body              string
+	BodyFile          string   // path suffix of the `< ./file` body
+	MultipartBody     string   // multipart/form-data body as sent, with its file parts
+	Assertions        []string // text of the `@assert` directives
+	PreRequestScript  string   // code of the `< {% %}` script
+	ResponseHandler   string   // code of the `> {% %}` script
 	Status            string
 	StatusCode        int
 }
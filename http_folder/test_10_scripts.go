@@ -0,0 +1,18 @@
+package testcases
+
+var Test_10_scripts = []RequestInfo{
+	{
+		Url:               "http://localhost:8080/auth",
+		Method:            "POST",
+		CommentIdentifier: "login",
+		Name:              "login",
+		Body:              "{\"user\": \"admin\", \"password\": \"{{password}}\"}\n\n",
+		PreRequestScript:  "request.variables.set(\"password\", \"admin\");",
+		ResponseHandler:   "client.global.set(\"token\", response.body.token);\n    client.test(\"logged in\", () => client.assert(response.status === 200));",
+	},
+	{
+		Url:             "http://localhost:8080/me",
+		Method:          "GET",
+		ResponseHandler: "client.test(\"me\", () => client.assert(response.body.name === \"admin\"));",
+	},
+}
@@ -0,0 +1,7 @@
+package testcases
+
+var Test_6_request_variables = []RequestInfo{
+	{Url: "http://localhost:8080/auth", Method: "POST", CommentIdentifier: "login", Name: "login", Body: "{\"user\":\"admin\",\"password\":\"admin\"}\n\n"},
+	{Url: "http://localhost:8080/users", Method: "POST", Name: "created"},
+	{Url: "{{created.response.headers.Location}}", Method: "GET"},
+}
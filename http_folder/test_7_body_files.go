@@ -0,0 +1,6 @@
+package testcases
+
+var Test_7_body_files = []RequestInfo{
+	{Url: "http://localhost:8080/import", Method: "POST", BodyFile: "fixtures/import.json"},
+	{Url: "http://localhost:8080/users", Method: "POST", BodyFile: "fixtures/user.json", Body: "{\"id\": 7, \"name\": \"seven\"}"},
+}
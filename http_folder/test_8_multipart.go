@@ -0,0 +1,19 @@
+package testcases
+
+var Test_8_multipart = []RequestInfo{
+	{
+		Url:    "http://localhost:8080/upload",
+		Method: "POST",
+		Body:   "------Boundary7MA4YWxk\nContent-Disposition: form-data; name=\"title\"\n\navatar\n------Boundary7MA4YWxk\nContent-Disposition: form-data; name=\"image\"; filename=\"pixel.png\"\nContent-Type: image/png\n\n< ./fixtures/pixel.png\n------Boundary7MA4YWxk--\n\n",
+		MultipartBody: "------Boundary7MA4YWxk\r\n" +
+			"Content-Disposition: form-data; name=\"title\"\r\n" +
+			"\r\n" +
+			"avatar\r\n" +
+			"------Boundary7MA4YWxk\r\n" +
+			"Content-Disposition: form-data; name=\"image\"; filename=\"pixel.png\"\r\n" +
+			"Content-Type: image/png\r\n" +
+			"\r\n" +
+			"\x89PNG\r\n\x1a\n\x00\x01\r\n\xff\r\n" +
+			"------Boundary7MA4YWxk--\r\n",
+	},
+}
@@ -0,0 +1,13 @@
+package testcases
+
+var Test_9_assertions = []RequestInfo{
+	{
+		Url:               "http://localhost:8080/users",
+		Method:            "POST",
+		CommentIdentifier: "create the user",
+		Name:              "created",
+		Body:              "{\"name\": \"Ada\"}\n\n",
+		Assertions:        []string{"status == 201", "header Content-Type matches ^application/json", "$.user.id > 0"},
+	},
+	{Url: "http://localhost:8080/users", Method: "GET", Assertions: []string{"status in 200..299", "duration < 500ms"}},
+}
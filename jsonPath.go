@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON decodes a body keeping numbers as json.Number, so they are printed back exactly as received
+func decodeJSON(body []byte) (any, error) {
+	var data any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// jsonPathStep is a key or an index of a JSONPath, [*] is every item of an array
+type jsonPathStep struct {
+	Key      string
+	Index    int // -1 for keys
+	Wildcard bool
+}
+
+// parseJSONPath splits a simple JSONPath expression in steps: $.user.roles[0]['display name']
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath must start with $: %s", path)
+	}
+	rest := path[1:]
+	var steps []jsonPathStep
+
+	for rest != "" {
+		step := jsonPathStep{Index: -1}
+
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			step.Key = rest[:end]
+			rest = rest[end:]
+			if step.Key == "" {
+				return nil, fmt.Errorf("empty key in JSONPath %s", path)
+			}
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("missing ] in JSONPath %s", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				step.Key = inner[1 : len(inner)-1]
+			} else if inner == "*" {
+				step.Wildcard = true
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index [%s] in JSONPath %s", inner, path)
+				}
+				step.Index = n
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in JSONPath %s", rest[0], path)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// evaluateJSONPath walks a decoded JSON value following a simple JSONPath
+// expression, supporting keys and indexes: $.user.roles[0]['display name']
+func evaluateJSONPath(data any, path string) (any, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	current := data
+
+	for _, step := range steps {
+		if step.Wildcard {
+			return nil, fmt.Errorf("[*] can't be used in JSONPath %s", path)
+		}
+		if step.Index >= 0 {
+			list, ok := current.([]any)
+			if !ok || step.Index >= len(list) {
+				return nil, fmt.Errorf("index [%d] not found in JSONPath %s", step.Index, path)
+			}
+			current = list[step.Index]
+			continue
+		}
+
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("key %q not found in JSONPath %s", step.Key, path)
+		}
+		value, ok := object[step.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found in JSONPath %s", step.Key, path)
+		}
+		current = value
+	}
+
+	return current, nil
+}
+
+// replaceJSONPath replaces in place every value the JSONPath points to, [*] goes
+// through all the items of an array. It returns how many values were replaced.
+func replaceJSONPath(data any, steps []jsonPathStep, value any) int {
+	if len(steps) == 0 {
+		return 0
+	}
+	step, last := steps[0], len(steps) == 1
+
+	switch current := data.(type) {
+	case map[string]any:
+		if step.Index >= 0 || step.Wildcard {
+			return 0
+		}
+		child, ok := current[step.Key]
+		if !ok {
+			return 0
+		}
+		if last {
+			current[step.Key] = value
+			return 1
+		}
+		return replaceJSONPath(child, steps[1:], value)
+	case []any:
+		replaced := 0
+		for i := range current {
+			if !step.Wildcard && i != step.Index {
+				continue
+			}
+			if last {
+				current[i] = value
+				replaced++
+			} else {
+				replaced += replaceJSONPath(current[i], steps[1:], value)
+			}
+		}
+		return replaced
+	}
+	return 0
+}
+
+// formatJSONValue turns a JSON value into the text used when it replaces a placeholder,
+// strings are used as they are and everything else as JSON
+func formatJSONValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"net/http"
@@ -47,19 +48,32 @@ const (
 )
 
 func main() {
+	// `lazyrequests history ...` only reads the history, it has its own flags
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		os.Exit(historyCommand(os.Args[2:]))
+	}
+
 	config, err := flagsConfig()
 	if err != nil {
 		log.Fatalf("Error parsing configuration: %v", err)
 	}
 	logVerbose(config, "Configuration loaded successfully")
 
+	if config.Run {
+		os.Exit(runOnce(config))
+	}
+
 	httpFileContentParsed, err := processHTTPFiles(config)
 	if err != nil {
 		fmt.Println(err)
 	}
 
-	// send requests at start
-	sendRequests(httpFileContentParsed, config)
+	// send requests at start, a change while they are sent cancels them
+	runs.restart(func() error { return nil }, func(ctx context.Context) {
+		if waitForServer(ctx, config) {
+			sendRequests(ctx, httpFileContentParsed, config)
+		}
+	})
 
 	// Create a new watcher.
 	w, err := fsnotify.NewWatcher()
@@ -70,7 +84,18 @@ func main() {
 	defer w.Close()
 
 	// Start listening for events.
-	go dedupLoop(w, config, httpFileContentParsed)
+	go dedupLoop(w, config, func(e fsnotify.Event) {
+		// reload the the HTTP Files since they've changed
+		httpFileContentParsed, err := reloadAndSend(config, nil)
+		if err != nil {
+			fmt.Printf("%sError reprocessing HTTP files: %v%s\n", C_Red, err, C_Reset)
+			return
+		}
+		// New `< ./file` bodies may have been added
+		watchInputFiles(w, httpFileContentParsed)
+		// HERE the magic happens
+		logVerbose(config, "Watching %s", e.String())
+	})
 
 	// Add all paths from the commandline.
 
@@ -85,87 +110,319 @@ func main() {
 		return
 	}
 
+	watchInputFiles(w, httpFileContentParsed)
+
+	// Commands typed while watching, like `env staging`
+	terminal.setCommandHandler(func(line string) {
+		runCommand(config, line)
+	})
+
 	<-make(chan struct{}) // Block forever
 
 }
 
-func sendRequests(httpFileContentParsed []HTTPFileContent, config *Config) {
-	currentTime := time.Now().Format("03:04 PM")
-	fmt.Printf("%s[%d] %s %s\n", C_Underline+C_Bold+C_Cyan, requestCount, currentTime, C_Reset)
+// sendRequests sends all the blocks once, the results go to the reporters of --reporter.
+// Once ctx is cancelled the requests in flight are aborted and nothing else is reported.
+func sendRequests(ctx context.Context, httpFileContentParsed []HTTPFileContent, config *Config) runSummary {
+	runStart := time.Now()
+	reporters := newReporters(config)
+	reporters.runStarted(requestCount, runStart)
+
+	// Blocks come after the ones they depend on, checked by processHTTPFiles
+	plan, err := planBlocks(httpFileContentParsed, config.Only, config.Parallel <= 1)
+	if err != nil {
+		fmt.Printf("%s%v%s\n", C_Red, err, C_Reset)
+	}
 
-	httpRequestTimeOut := time.Duration(config.HTTPRequestTimeout) * time.Millisecond
 	waitRequestTime := config.SleepTime * int(time.Millisecond)
-	for j, fileContent := range httpFileContentParsed {
-		for k, block := range fileContent.Blocks {
+	state := newRunState()
+	collector := newResultCollector(plan, reporters)
+	runBlocks(plan, config.Parallel, func(p int, failedDependency int) string {
+		if ctx.Err() != nil {
+			return outcomeSkipped
+		}
+		fileContent := httpFileContentParsed[plan[p].file]
+		block := fileContent.Blocks[plan[p].block]
+
+		var result blockResult
+		if failedDependency >= 0 {
+			dependency := plan[failedDependency]
+			result = blockResult{
+				File:    fileContent.FilePath,
+				Block:   block,
+				Method:  block.Request.Method,
+				URL:     block.Request.Url,
+				Outcome: outcomeSkipped,
+				Err:     fmt.Errorf("%s didn't pass", blockLabel(httpFileContentParsed, dependency.blockRef)),
+			}
+		} else {
 			// Add a sleep, to allow server to initialize and in between requests
-			time.Sleep(time.Duration(waitRequestTime)) // Fixed 100ms wait between requests
+			sleepContext(ctx, time.Duration(waitRequestTime))
+			result = sendBlock(ctx, fileContent, block, config, state)
+		}
+		// The results of a cancelled run aren't reported, they'd mix with the next run
+		if ctx.Err() != nil {
+			return outcomeSkipped
+		}
+		collector.add(p, result)
+		return result.Outcome
+	})
+
+	summary := collector.summary
+	summary.Duration = time.Since(runStart)
+	summary.Cancelled = ctx.Err() != nil
+	reporters.runFinished(summary, collector.results)
+	return summary
+}
 
-			reqDetails := block.Request
-			ctx, cancel := context.WithTimeout(context.Background(), httpRequestTimeOut)
+// sendBlock sends a block and checks its response
+func sendBlock(ctx context.Context, fileContent HTTPFileContent, block HTTPBlock, config *Config, state *runState) blockResult {
+	result := blockResult{
+		File:   fileContent.FilePath,
+		Block:  block,
+		Method: block.Request.Method,
+		URL:    block.Request.Url,
+	}
+	errored := func(err error) blockResult {
+		result.Outcome = outcomeErrored
+		result.Err = err
+		return result
+	}
 
-			newReq, err := http.NewRequestWithContext(ctx, reqDetails.Method, reqDetails.Url, strings.NewReader(reqDetails.Body))
-			if err != nil {
-				cancel()
-				fmt.Printf("error at creating request: httpFileContentParsed[%d][%d]: %v", j, k, err)
-			}
-			// Add headers
-			for key, value := range reqDetails.Headers {
-				newReq.Header.Set(key, value)
-			}
-			// Start timing the request
-			startTime := time.Now()
+	if block.Note && !config.Yes {
+		if !stdinIsTerminal() {
+			// Nobody could answer, asking would wait forever on a pipe that stays open
+			result.Outcome = outcomeSkipped
+			result.Err = fmt.Errorf("not confirmed (non-interactive)")
+			return result
+		}
+		if !confirmSend(ctx, block) {
+			result.Outcome = outcomeSkipped
+			return result
+		}
+	}
+
+	// Variables set by the `< {% %}` script with request.variables.set
+	var scriptVariables map[string]string
+	if block.PreRequestScript != "" {
+		var err error
+		scriptVariables, err = runPreRequestScript(block.PreRequestScript, block.Request, fileContent)
+		if err != nil {
+			return errored(fmt.Errorf("pre-request script: %w", err))
+		}
+	}
+
+	reqDetails, err := resolvePromptVariables(ctx, block.Request, block, config)
+	if err != nil {
+		return errored(err)
+	}
+	result.Secrets = secretPromptValues(block, config)
+	reqDetails = resolveScriptVariables(reqDetails, scriptVariables)
+	reqDetails, errs := state.resolveRequestVariables(reqDetails)
+	reqDetails, systemErrs := resolveSystemVariables(reqDetails, fileContent)
+	errs = append(errs, systemErrs...)
+	errs = append(errs, unresolvedVariables(reqDetails)...)
+	for _, err := range errs {
+		result.Warnings = append(result.Warnings, err.Error())
+	}
+	result.Method, result.URL = reqDetails.Method, reqDetails.Url
 
-			client := &http.Client{}
-			resp, err := client.Do(newReq)
+	retry := blockRetryPolicy(block, config)
+	if retry.Err != nil {
+		return errored(fmt.Errorf("@retry: %w", retry.Err))
+	}
 
-			// Calculate elapsed time
-			elapsedTime := time.Since(startTime)
-			elapsedMs := elapsedTime.Milliseconds()
+	result.RequestBody = reqDetails.Body
+	if reqDetails.BodyFile != "" && !reqDetails.BodyFileVariables {
+		result.RequestBody, result.RequestBodyFile = "", reqDetails.BodyFile
+	} else if reqDetails.Multipart {
+		multipartBody, err := buildMultipartBody(reqDetails.Body, filepath.Dir(fileContent.FilePath))
+		if err != nil {
+			return errored(err)
+		}
+		result.RequestBody = string(multipartBody)
+	} else if reqDetails.GraphQL {
+		graphQLBody, err := buildGraphQLBody(reqDetails.Body)
+		if err != nil {
+			return errored(err)
+		}
+		result.RequestBody = string(graphQLBody)
+	}
 
+	// newRequest builds the request of an attempt, a retry needs a fresh body
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		var requestBody io.Reader = strings.NewReader(result.RequestBody)
+		var bodyLength int64 = -1
+		if result.RequestBodyFile != "" {
+			// Stream `< ./file` bodies instead of loading them
+			file, err := os.Open(result.RequestBodyFile)
 			if err != nil {
-				fileName := filepath.Base(fileContent.FilePath)
-				logVerbose(config, "Error at main.go: client := &http.Client{}")
-				fmt.Printf("%s: %s%v%s\n", fileName, C_Red, err, C_Reset)
-				cancel()
-				continue
+				return nil, err
 			}
-			results := struct {
-				OK       bool
-				MSG      string
-				Expected string
-				Got      string
-			}{
-				OK: true,
+			if info, err := file.Stat(); err == nil {
+				bodyLength = info.Size()
 			}
+			requestBody = file
+		}
 
-			if block.ExpectedResponse != nil { // if response
-				if block.ExpectedResponse.Status != "" { // if expected response status
-					// if response status are the same
-					if block.ExpectedResponse.Status != resp.Status {
-						results.OK = false
-						results.MSG = "Response Status mismatch"
-						results.Expected = block.ExpectedResponse.Status
-						results.Got = resp.Status
-					}
-				}
+		newReq, err := http.NewRequestWithContext(ctx, reqDetails.Method, reqDetails.Url, requestBody)
+		if err != nil {
+			if closer, ok := requestBody.(io.Closer); ok {
+				closer.Close()
 			}
+			return nil, fmt.Errorf("error at creating request: %w", err)
+		}
+		if bodyLength >= 0 {
+			newReq.ContentLength = bodyLength
+		}
+		// Add headers
+		for key, value := range reqDetails.Headers {
+			newReq.Header.Set(key, value)
+		}
+		return newReq, nil
+	}
+
+	httpRequestTimeOut := time.Duration(config.HTTPRequestTimeout) * time.Millisecond
+	client := newHTTPClient(block, state.jar)
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		// Each attempt gets the whole timeout
+		attemptCtx, cancel := context.WithTimeout(ctx, httpRequestTimeOut)
+
+		newReq, err := newRequest(attemptCtx)
+		if err != nil {
+			cancel()
+			return errored(err)
+		}
+		result.RequestHeaders = newReq.Header
+		result.Attempts = attempt
+
+		// Start timing the request
+		startTime := time.Now()
+		resp, err = client.Do(newReq)
+		// Calculate elapsed time
+		result.Duration = time.Since(startTime)
 
-			if results.OK {
-				if block.CommentIdentifier != "" {
-					fmt.Printf("%s%s%s\n", C_Purple, block.CommentIdentifier, C_Reset)
-				}
-				//fmt.Printf("%10s %4s %s%s%dms %s%s%s\n", C_Bold+C_Blue+resp.Request.Method+C_Reset, C_Green, resp.Status, C_Yellow, elapsedMs, C_Gray, resp.Request.URL, C_Reset)
-				fmt.Printf("%s%-6s %s%-12s %s%3dms %s%s\n", C_Bold+C_Blue, resp.Request.Method, C_Reset+C_Green, resp.Status, C_Yellow, elapsedMs, C_Gray, resp.Request.URL)
-			} else {
-				fmt.Printf("%s[X][ %s ] Expected: [ %s ] Got: [ %s ] %s \n", C_Red, results.MSG, results.Expected, results.Got, C_Reset)
+		if attempt > retry.Retries || ctx.Err() != nil || !retry.shouldRetry(reqDetails.Method, resp, err) {
+			if err != nil {
+				cancel()
+				logVerbose(config, "Error at main.go: client := &http.Client{}")
+				return errored(err)
 			}
-			// LOG IF WINS
+			// The body of the last attempt is read below, under its timeout
+			defer cancel()
+			break
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
 			resp.Body.Close()
-			cancel()
 		}
+		// Release the timer of this attempt before waiting for the next one
+		cancel()
+		delay := retry.delay(attempt)
+		logVerbose(config, "Retrying %s %s in %s, attempt %d failed", reqDetails.Method, reqDetails.Url, delay, attempt)
+		if !sleepContext(ctx, delay) {
+			return errored(ctx.Err())
+		}
+	}
+	defer resp.Body.Close()
+	elapsedTime := result.Duration
+	result.Method, result.URL, result.Status = resp.Request.Method, resp.Request.URL.String(), resp.Status
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logVerbose(config, "Error reading response body: %v", err)
+	}
+	// The transport only decodes the gzip it asked for, not the encodings asked with Accept-Encoding
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" {
+		if body, err = decodeBody(body, encoding); err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+		}
+	}
+	result.ResponseHeaders, result.ResponseBody = resp.Header, body
+	if block.Name != "" {
+		state.saveExchange(block.Name, &requestExchange{
+			Request:         reqDetails,
+			Status:          resp.Status,
+			ResponseHeaders: resp.Header,
+			ResponseBody:    body,
+		})
+	}
+
+	var failures []assertionFailure
+	if block.ExpectedResponse != nil { // if response
+		failures = append(failures, compareResponse(block.ExpectedResponse, block.ExpectedResponseBody, resp, body)...)
+	}
+
+	if reqDetails.GraphQL {
+		if messages := graphQLErrors(body); len(messages) > 0 {
+			failures = append(failures, assertionFailure{Field: "GraphQL errors", Expected: "no errors", Got: strings.Join(messages, "; ")})
+		}
+	}
+
+	if block.Schema != "" {
+		failures = append(failures, validateSchema(block.Schema, body)...)
+	}
+
+	snapshotFile := snapshotPath(fileContent.FilePath, block)
+	if config.SnapshotUpdate {
+		if err := saveSnapshot(snapshotFile, resp, body, block.SnapshotIgnore); err != nil {
+			failures = append(failures, assertionFailure{Field: "snapshot", Message: err.Error()})
+		} else {
+			result.SnapshotSaved = true
+		}
+	} else {
+		snapshotFailures, diff := compareSnapshot(snapshotFile, resp, body, block.SnapshotIgnore)
+		failures = append(failures, snapshotFailures...)
+		result.SnapshotDiff = diff
+	}
+
+	assertions := evaluateAssertions(block.Assertions, resp, body, elapsedTime)
+	if block.ResponseHandler != "" {
+		tests, err := runResponseHandler(block.ResponseHandler, reqDetails, fileContent, resp, body)
+		assertions = append(assertions, tests...)
+		if err != nil {
+			failures = append(failures, assertionFailure{Field: "response handler", Message: err.Error()})
+		}
+	}
+
+	result.Failures, result.Assertions = failures, assertions
+	result.Outcome = outcomePassed
+	if len(failures) > 0 || !assertionsPassed(assertions) {
+		result.Outcome = outcomeFailed
+	}
+	return result
+}
+
+// newHTTPClient returns the client for a block following its `// @no-redirect` and `// @no-cookie-jar` directives
+func newHTTPClient(block HTTPBlock, jar http.CookieJar) *http.Client {
+	client := &http.Client{}
+	if !block.NoCookieJar {
+		client.Jar = jar
+	}
+	if block.NoRedirect {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client
+}
+
+// confirmSend asks before sending a `// @note` block, without a terminal to answer it's not sent
+func confirmSend(ctx context.Context, block HTTPBlock) bool {
+	question := fmt.Sprintf("%sSend %s %s? [y/N] %s", C_Yellow, block.Request.Method, block.Request.Url, C_Reset)
+	if block.CommentIdentifier != "" {
+		question = fmt.Sprintf("%s%s%s\n%s", C_Purple, block.CommentIdentifier, C_Reset, question)
+	}
+	answer, err := terminal.ask(ctx, question)
+	if err != nil {
+		fmt.Println()
+		return false
 	}
-	fmt.Printf("%sdone.%s\n", C_Gray, C_Reset)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
+
 func runCmd(name string, arg ...string) {
 	cmd := exec.Command(name, arg...)
 	cmd.Stdout = os.Stdout
@@ -184,7 +441,106 @@ func ClearTerminal() {
 	}
 }
 
-func dedupLoop(w *fsnotify.Watcher, config *Config, httpFileContentParsed []HTTPFileContent) {
+// reloadAndSend cancels the run in flight, parses the HTTP files again and sends all the
+// requests in the background. change, when given, updates config once the previous run
+// stopped and returns how to undo it when the files can't be parsed, both under the lock of runs.
+func reloadAndSend(config *Config, change func() (undo func())) ([]HTTPFileContent, error) {
+	var httpFileContentParsed []HTTPFileContent
+	err := runs.restart(func() error {
+		var undo func()
+		if change != nil {
+			undo = change()
+		}
+		var err error
+		httpFileContentParsed, err = processHTTPFiles(config)
+		if err != nil && undo != nil {
+			undo()
+		}
+		return err
+	}, func(ctx context.Context) {
+		// Clear terminal and increase request count
+		ClearTerminal()
+		requestCount++
+
+		// Send the HTTP requests once the server is up
+		if waitForServer(ctx, config) {
+			sendRequests(ctx, httpFileContentParsed, config)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return httpFileContentParsed, nil
+}
+
+// watchInputFiles watches the files the requests read besides the watched path,
+// environment files and `< ./file` bodies or parts, so changing them re-runs everything as well
+func watchInputFiles(w *fsnotify.Watcher, httpFileContentParsed []HTTPFileContent) {
+	var paths []string
+	for _, fileContent := range httpFileContentParsed {
+		paths = append(paths, environmentFilePaths(fileContent.FilePath)...)
+		for _, block := range fileContent.Blocks {
+			if block.Request.BodyFile != "" {
+				paths = append(paths, block.Request.BodyFile)
+			}
+			if block.Request.Multipart {
+				paths = append(paths, multipartFiles(block.Request.Body, filepath.Dir(fileContent.FilePath))...)
+			}
+			if block.Schema != "" {
+				paths = append(paths, schemaFiles(block.Schema)...)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := w.Add(path); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// writtenPaths are the absolute paths of the files lazyrequests writes itself: the history
+// and the reports. Writing them must not send the requests again.
+func writtenPaths(config *Config) map[string]bool {
+	paths := []string{config.HTMLReport, config.HistoryFile}
+	for _, rc := range config.Reporters {
+		paths = append(paths, rc.Path)
+	}
+
+	written := make(map[string]bool)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if absolute, err := filepath.Abs(path); err == nil {
+			written[absolute] = true
+		}
+	}
+	return written
+}
+
+// isWrittenPath tells if an event comes from a file lazyrequests wrote, snapshots included
+func isWrittenPath(name string, written map[string]bool) bool {
+	absolute, err := filepath.Abs(name)
+	if err != nil {
+		return false
+	}
+	if written[absolute] {
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(absolute), "/") {
+		if part == snapshotDir {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupLoop calls run once the events of a path stop for a moment
+func dedupLoop(w *fsnotify.Watcher, config *Config, run func(e fsnotify.Event)) {
 	var (
 		// Wait 100ms for new events; each new event resets the timer.
 		waitFor = 100 * time.Millisecond
@@ -195,28 +551,15 @@ func dedupLoop(w *fsnotify.Watcher, config *Config, httpFileContentParsed []HTTP
 
 		// Callback we run.
 		printEvent = func(e fsnotify.Event) {
-			// reload the the HTTP Files since they've changed
-			newHttpFileContentParsed, err := processHTTPFiles(config)
-			if err != nil {
-				fmt.Printf("%sError reprocessing HTTP files: %v%s\n", C_Red, err, C_Reset)
-				return
-			}
-			// Update the parsed content
-			httpFileContentParsed = newHttpFileContentParsed
-			// Clear terminal and increase request count
-			ClearTerminal()
-			requestCount++
-
-			// Send the HTTP requests
-			sendRequests(httpFileContentParsed, config)
-			// HERE the magic happens
-			logVerbose(config, "Watching", e.String())
+			run(e)
 
 			// Don't need to remove the timer if you don't have a lot of files.
 			mu.Lock()
 			delete(timers, e.Name)
 			mu.Unlock()
 		}
+
+		written = writtenPaths(config)
 	)
 
 	for {
@@ -238,6 +581,10 @@ func dedupLoop(w *fsnotify.Watcher, config *Config, httpFileContentParsed []HTTP
 			if !e.Has(fsnotify.Create) && !e.Has(fsnotify.Write) {
 				continue
 			}
+			// Writing snapshots, reports or the history shouldn't send the requests again
+			if isWrittenPath(e.Name, written) {
+				continue
+			}
 
 			// Get timer.
 			mu.Lock()
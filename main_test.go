@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		http.Redirect(w, r, "/home", http.StatusFound)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		block    HTTPBlock
+		path     string
+		expected int
+	}{
+		{"follows redirects", HTTPBlock{}, "/login", http.StatusOK},
+		{"no redirect", HTTPBlock{NoRedirect: true}, "/login", http.StatusFound},
+		{"shares cookies", HTTPBlock{}, "/home", http.StatusOK},
+		{"no cookie jar", HTTPBlock{NoCookieJar: true}, "/home", http.StatusUnauthorized},
+	}
+
+	jar, _ := cookiejar.New(nil)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := newHTTPClient(tc.block, jar).Get(server.URL + tc.path)
+			if err != nil {
+				t.Fatalf("got error on request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.expected {
+				t.Errorf("Incorrect status. expected: %d, Got: %d", tc.expected, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestDedupLoopIgnoresWrittenFiles(t *testing.T) {
+	// Watching the working directory with relative report paths, like `--watch-folder .`
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.Add("."); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		HTMLReport:  "report.html",
+		HistoryFile: "requests.jsonl",
+		Reporters:   []reporterConfig{{Name: "junit", Path: filepath.Join(dir, "junit.xml")}},
+	}
+	runs := make(chan string, 10)
+	go dedupLoop(w, config, func(e fsnotify.Event) {
+		runs <- filepath.Base(e.Name)
+	})
+
+	for _, name := range []string{"report.html", "requests.jsonl", "junit.xml"} {
+		if err := os.WriteFile(name, []byte("report"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(snapshotDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case name := <-runs:
+		t.Fatalf("writing %s shouldn't start a run", name)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if err := os.WriteFile("api.http", []byte("GET http://localhost HTTP/1.1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case name := <-runs:
+		if name != "api.http" {
+			t.Errorf("expected a run for api.http, Got: %s", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("editing api.http should start a run")
+	}
+}
+
+func TestNoteWithoutTerminal(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+	}))
+	defer server.Close()
+
+	isTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = isTerminal }()
+
+	config := &Config{HTTPRequestTimeout: 2000}
+	httpFileContent, err := writeHTTPFiles(t, config, map[string]string{"api.http": fmt.Sprintf("// @note\nDELETE %s/users/1 HTTP/1.1", server.URL)})
+	if err != nil {
+		t.Fatalf("got error on function processHTTPFiles: %v", err)
+	}
+	block := httpFileContent[0].Blocks[0]
+
+	result := sendBlock(context.Background(), httpFileContent[0], block, config, newRunState())
+	if result.Outcome != outcomeSkipped || result.skipReason() != "not confirmed (non-interactive)" || sent != 0 {
+		t.Errorf("a note shouldn't be sent without a terminal, Got: %s %q after %d requests", result.Outcome, result.skipReason(), sent)
+	}
+
+	config.Yes = true
+	if result := sendBlock(context.Background(), httpFileContent[0], block, config, newRunState()); result.Outcome != outcomePassed || sent != 1 {
+		t.Errorf("--yes should send the note, Got: %s after %d requests", result.Outcome, sent)
+	}
+}
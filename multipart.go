@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// headerValue finds a header ignoring the case of its name
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// isMultipartFormData checks a Content-Type header, multipart/form-data must come with its boundary
+func isMultipartFormData(contentType string) (bool, error) {
+	if contentType == "" {
+		return false, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		return false, nil
+	}
+	if params["boundary"] == "" {
+		return false, fmt.Errorf("multipart/form-data Content-Type without boundary: %s", contentType)
+	}
+	return true, nil
+}
+
+// buildMultipartBody turns a multipart/form-data body written in an .http file into
+// the bytes sent: lines are joined with CRLF and `< ./file` lines are replaced
+// by the content of the file, relative to baseDir
+//
+//	--boundary
+//	Content-Disposition: form-data; name="avatar"; filename="avatar.png"
+//	Content-Type: image/png
+//
+//	< ./avatar.png
+//	--boundary--
+func buildMultipartBody(body string, baseDir string) ([]byte, error) {
+	var result bytes.Buffer
+	lines := strings.Split(strings.TrimRight(body, "\r\n"), "\n")
+
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if path, ok := parseMultipartFileLine(line, baseDir); ok {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading multipart file: %w", err)
+			}
+			result.Write(content)
+		} else {
+			result.WriteString(line)
+		}
+		result.WriteString("\r\n")
+	}
+
+	return result.Bytes(), nil
+}
+
+// multipartFiles returns the files referenced by the parts of a multipart/form-data body
+func multipartFiles(body string, baseDir string) []string {
+	var paths []string
+	for _, line := range strings.Split(body, "\n") {
+		if path, ok := parseMultipartFileLine(strings.TrimSuffix(line, "\r"), baseDir); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func parseMultipartFileLine(line string, baseDir string) (string, bool) {
+	if !strings.HasPrefix(line, "< ") {
+		return "", false
+	}
+	path := strings.TrimSpace(line[2:])
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return path, true
+}
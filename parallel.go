@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"slices"
+	"sync"
+)
+
+// runState is what the blocks of a run share, the workers of --parallel use it at the same time
+type runState struct {
+	mu sync.Mutex
+	// Responses of the blocks with `// @name`, for request variables
+	exchanges map[string]*requestExchange
+	// Cookies are shared by all the requests of a run, cookiejar is safe for concurrent use
+	jar http.CookieJar
+}
+
+func newRunState() *runState {
+	jar, _ := cookiejar.New(nil)
+	return &runState{exchanges: make(map[string]*requestExchange), jar: jar}
+}
+
+func (s *runState) resolveRequestVariables(req HTTPRequest) (HTTPRequest, []error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return resolveRequestVariables(req, s.exchanges)
+}
+
+func (s *runState) saveExchange(name string, exchange *requestExchange) {
+	s.mu.Lock()
+	s.exchanges[name] = exchange
+	s.mu.Unlock()
+}
+
+// runBlocks sends the blocks of the plan with up to workers at the same time, a block waits for the
+// blocks it comes after. run gets the failed dependency of a block that can't be sent and returns its outcome.
+func runBlocks(plan []plannedBlock, workers int, run func(p int, failedDependency int) string) {
+	var (
+		mu         sync.Mutex
+		wake       = sync.NewCond(&mu)
+		waiting    = make([]int, len(plan))
+		dependants = make([][]int, len(plan))
+		outcomes   = make([]string, len(plan))
+		ready      []int // plan indexes that can be sent, in plan order
+		started    int
+	)
+	for p, planned := range plan {
+		waiting[p] = len(planned.after)
+		for _, before := range planned.after {
+			dependants[before] = append(dependants[before], p)
+		}
+		if waiting[p] == 0 {
+			ready = append(ready, p)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range max(min(workers, len(plan)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			for {
+				for len(ready) == 0 && started < len(plan) {
+					wake.Wait()
+				}
+				if started == len(plan) {
+					return
+				}
+				p := ready[0]
+				ready = ready[1:]
+				started++
+
+				failedDependency := -1
+				for _, dependency := range plan[p].dependsOn {
+					if outcomes[dependency] != outcomePassed {
+						failedDependency = dependency
+						break
+					}
+				}
+
+				mu.Unlock()
+				outcome := run(p, failedDependency)
+				mu.Lock()
+
+				outcomes[p] = outcome
+				for _, dependant := range dependants[p] {
+					if waiting[dependant]--; waiting[dependant] == 0 {
+						index, _ := slices.BinarySearch(ready, dependant)
+						ready = slices.Insert(ready, index, dependant)
+					}
+				}
+				wake.Broadcast()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// resultCollector hands the results to the reporters in the order of the plan, whatever order
+// they finish in, so the output stays grouped by file as if the blocks were sent one by one
+type resultCollector struct {
+	mu        sync.Mutex
+	reporters reporters
+	pending   []*blockResult // by plan index, until it's their turn
+	next      int
+	summary   runSummary
+	results   []blockResult
+}
+
+func newResultCollector(plan []plannedBlock, reporters reporters) *resultCollector {
+	return &resultCollector{reporters: reporters, pending: make([]*blockResult, len(plan))}
+}
+
+func (c *resultCollector) add(p int, result blockResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[p] = &result
+	for c.next < len(c.pending) && c.pending[c.next] != nil {
+		next := *c.pending[c.next]
+		c.pending[c.next] = nil
+		c.next++
+
+		c.summary.add(next)
+		c.results = append(c.results, next)
+		c.reporters.blockFinished(next)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSendRequestsParallel(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[string]int) // by file
+		maxTotal int
+		maxFile  = make(map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file := strings.Split(r.URL.Path, "/")[1]
+		mu.Lock()
+		inFlight[file]++
+		total := 0
+		for _, n := range inFlight {
+			total += n
+		}
+		maxTotal = max(maxTotal, total)
+		maxFile[file] = max(maxFile[file], inFlight[file])
+		mu.Unlock()
+
+		time.Sleep(30 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": %q}`, r.URL.Path)
+
+		mu.Lock()
+		inFlight[file]--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		// Blocks of a file are sent in order, so the second one sees the first one's response
+		"a.http": "// @name first\nGET %[1]s/a/1 HTTP/1.1\n###\n// @assert $.path == /a/a/1\nGET %[1]s/a{{first.response.body.$.path}} HTTP/1.1\n###\nGET %[1]s/a/3 HTTP/1.1",
+		"b.http": "GET %[1]s/b/1 HTTP/1.1\n###\nGET %[1]s/b/2 HTTP/1.1",
+		"c.http": "// @parallel\nGET %[1]s/c/1 HTTP/1.1\n###\nGET %[1]s/c/2 HTTP/1.1\n###\nGET %[1]s/c/3 HTTP/1.1\n###\nGET %[1]s/c/4 HTTP/1.1",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(fmt.Sprintf(content, server.URL)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := filepath.Join(dir, "report.json")
+	config := &Config{HTTPFolderPath: dir, HTTPRequestTimeout: 2000, Parallel: 4, Reporters: []reporterConfig{{Name: "json", Path: report}}}
+	httpFileContent, err := processHTTPFiles(config)
+	if err != nil {
+		t.Fatalf("got error on function processHTTPFiles: %v", err)
+	}
+	if httpFileContent[0].Parallel || httpFileContent[1].Parallel || !httpFileContent[2].Parallel {
+		t.Fatalf("only c.http should be parallel")
+	}
+
+	summary := sendRequests(context.Background(), httpFileContent, config)
+	if summary.Passed != 9 || !summary.ok() {
+		t.Errorf("Incorrect summary: %+v", summary)
+	}
+	if maxTotal < 2 || maxTotal > 4 {
+		t.Errorf("expected between 2 and 4 requests at the same time, Got: %d", maxTotal)
+	}
+	if maxFile["a"] != 1 || maxFile["b"] != 1 || maxFile["c"] < 2 {
+		t.Errorf("Incorrect requests at the same time by file: %v", maxFile)
+	}
+
+	// Results stay in the order of the files and blocks
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed jsonReport
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, result := range parsed.Results {
+		order = append(order, fmt.Sprintf("%s#%d", filepath.Base(result.File), result.Block))
+	}
+	expected := "a.http#1 a.http#2 a.http#3 b.http#1 b.http#2 c.http#1 c.http#2 c.http#3 c.http#4"
+	if strings.Join(order, " ") != expected {
+		t.Errorf("Incorrect order. expected: %s, Got: %s", expected, strings.Join(order, " "))
+	}
+}
+
+func TestResultCollector(t *testing.T) {
+	plan := make([]plannedBlock, 3)
+	var finished []string
+	collector := newResultCollector(plan, reporters{funcReporter(func(result blockResult) {
+		finished = append(finished, result.URL)
+	})})
+
+	collector.add(2, blockResult{URL: "2", Outcome: outcomePassed})
+	collector.add(1, blockResult{URL: "1", Outcome: outcomeFailed})
+	if len(finished) != 0 {
+		t.Fatalf("nothing can be reported before the first block, Got: %v", finished)
+	}
+	collector.add(0, blockResult{URL: "0", Outcome: outcomePassed})
+	if strings.Join(finished, " ") != "0 1 2" {
+		t.Errorf("Incorrect order: %v", finished)
+	}
+	if collector.summary.Passed != 2 || collector.summary.Failed != 1 || len(collector.results) != 3 {
+		t.Errorf("Incorrect summary: %+v", collector.summary)
+	}
+}
+
+// funcReporter is a reporter that only looks at the finished blocks
+type funcReporter func(result blockResult)
+
+func (f funcReporter) runStarted(run int, start time.Time)                         {}
+func (f funcReporter) blockFinished(result blockResult)                            { f(result) }
+func (f funcReporter) runFinished(summary runSummary, results []blockResult) error { return nil }
@@ -18,24 +18,58 @@ type HTTPRequest struct {
 	HTTPVersion string
 	Headers     map[string]string
 	Body        string
+	// absolute path of the file sent as the body with `< ./file` or `<@ ./file`
+	BodyFile string
+	// `<@ ./file`, the file is read into Body with its variables replaced, otherwise it's streamed when sent
+	BodyFileVariables bool
+	// multipart/form-data body, its `< ./file` parts are read when sent, see buildMultipartBody
+	Multipart bool
+	// `X-Request-Type: GraphQL` block, the body is wrapped when sent, see buildGraphQLBody
+	GraphQL bool
 }
 
 type HTTPFileContent struct {
 	RawContent      string
 	FilePath        string
 	GlobalVariables map[string]string
-	Blocks          []HTTPBlock
+	// variables of the selected environment, see loadEnvironmentVariables
+	EnvironmentVariables map[string]string
+	Blocks               []HTTPBlock
+	Parallel             bool // `// @parallel` in the first block, its blocks are sent concurrently with --parallel
 }
 
 type HTTPBlock struct {
 	ID                int
 	BlockContent      string // represents the raw string request
 	CommentIdentifier string
+	Name              string           // set by `// @name login`, used to reference this block from later ones
+	Directives        []BlockDirective // `// @...` lines found above the request line
+	NoRedirect        bool             // `// @no-redirect`, redirects are returned instead of followed
+	NoCookieJar       bool             // `// @no-cookie-jar`, cookies of the run are neither sent nor saved
+	Note              bool             // `// @note`, asks for confirmation before sending
+	Prompts           []BlockPrompt    // `// @prompt name description`, asked before sending
+	Assertions        []blockAssertion // `# @assert status == 201`, checked after the response is read
+	Schema            string           // `// @schema ./user.json`, JSON Schema the response body must follow
+	SnapshotIgnore    []string         // `// @snapshot-ignore $.createdAt`, JSONPaths left out of the snapshot
+	DependsOn         []string         // `// @depends-on login`, blocks sent before this one, it's skipped when they don't pass
+	Parallel          bool             // `// @parallel`, in the first block it lets --parallel send the blocks of the file concurrently
+	ShowBody          bool             // `// @show`, prints the response body like --show-body
+	ShowHeaders       bool             // `// @show headers`, prints the response headers, `// @show body headers` both
+	Retry             *retryPolicy     // `// @retry 5 200ms on 502,503`, instead of --retries and --retry-backoff
+	PreRequestScript  string           // `< {% ... %}` above the request line, run before sending
+	ResponseHandler   string           // `> {% ... %}` after the request, run once the response is read
 	Request           HTTPRequest
 	//Request                *http.Request // represents the parsed request ready to be sent
 	RequestString          string
 	ExpectedResponse       *http.Response // represents the expected response to be compared with
 	ExpectedResponseString string
+	ExpectedResponseBody   string // body of the expected response, ExpectedResponse.Body can only be read once
+}
+
+// BlockDirective is a `// @directive value` (or `# @directive value`) line written above the request line
+type BlockDirective struct {
+	Name  string
+	Value string
 }
 
 type HTTPResponse struct {
@@ -51,6 +85,10 @@ func processHTTPFiles(config *Config) ([]HTTPFileContent, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error at parseHTTPFiles.go - getRawContent() %w", err)
 	}
+	httpFileContent, err = loadEnvironmentVariables(httpFileContent, config)
+	if err != nil {
+		return nil, fmt.Errorf("error at parseHTTPFiles.go - loadEnvironmentVariables() %w", err)
+	}
 	httpFileContent, err = removeComments(httpFileContent)
 	if err != nil {
 		return nil, fmt.Errorf("error at parseHTTPFiles.go - removeComments() %w", err)
@@ -86,6 +124,10 @@ func processHTTPFiles(config *Config) ([]HTTPFileContent, error) {
 	httpFileContent, err = removeHTTPBlockResponsesFromHttpFileContent(httpFileContent, config)
 	if err != nil {
 
+	}
+	httpFileContent, err = checkBlockDependencies(httpFileContent, config)
+	if err != nil {
+		return nil, fmt.Errorf("error at parseHTTPFiles.go - checkBlockDependencies() %w", err)
 	}
 	return httpFileContent, nil
 }
@@ -94,7 +136,8 @@ func processHTTPFiles(config *Config) ([]HTTPFileContent, error) {
 func isHTTPResponseLine(line string) bool {
 	// HTTP response must start with HTTP protocol version followed by status code
 	// Pattern: HTTP/X.X XXX ...
-	pattern := regexp.MustCompile(`^HTTP/\d\.\d\s+\d{3}`)
+	// The status can be a class like 2xx in expected responses
+	pattern := regexp.MustCompile(`^HTTP/\d(\.\d)?\s+([1-5](\d\d|xx|XX))\b`)
 	return pattern.MatchString(line)
 }
 func checkNormalizationOnBlocks(httpFileContent []HTTPFileContent, config *Config) ([]HTTPFileContent, error) {
@@ -312,11 +355,28 @@ func parseHTTPBlockRequests(httpFileContent []HTTPFileContent, config *Config) (
 			}
 
 			// I do this to add the Content-Length properly
-			ptr, err := stringToHTTPStruct(block.BlockContent)
+			ptr, err := stringToHTTPStruct(block.BlockContent, file.FilePath)
 			if err != nil {
 				return nil, fmt.Errorf("error parsing HTTP request in file %s, block %d: %w", file.FilePath, block.ID, err)
 			}
 
+			// `<@ ./file` bodies get the same variables as the .http file
+			if ptr.BodyFileVariables {
+				content, err := os.ReadFile(ptr.BodyFile)
+				if err != nil {
+					return nil, fmt.Errorf("error reading body file in file %s, block %d: %w", file.FilePath, block.ID, err)
+				}
+				ptr.Body = replaceFileVariables(string(content), file.GlobalVariables, file.EnvironmentVariables)
+				if _, exists := ptr.Headers["Content-Length"]; !exists {
+					ptr.Headers["Content-Length"] = strconv.Itoa(len(ptr.Body))
+				}
+			}
+
+			// `// @schema ./file` is relative to the .http file too
+			if block.Schema != "" && !filepath.IsAbs(block.Schema) {
+				httpFileContent[i].Blocks[j].Schema = filepath.Join(filepath.Dir(file.FilePath), block.Schema)
+			}
+
 			// Create a new http request with proper timeout
 			// ctx := context.Background()
 			// timeout := 5 * time.Second
@@ -345,7 +405,11 @@ func parseHTTPBlockRequests(httpFileContent []HTTPFileContent, config *Config) (
 				requestStr.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
 			}
 			requestStr.WriteString("\r\n")
-			requestStr.WriteString(ptr.Body)
+			if ptr.BodyFile != "" && !ptr.BodyFileVariables {
+				requestStr.WriteString("< " + ptr.BodyFile)
+			} else {
+				requestStr.WriteString(ptr.Body)
+			}
 
 			// Add request to the block
 			httpFileContent[i].Blocks[j].Request = ptr
@@ -356,34 +420,31 @@ func parseHTTPBlockRequests(httpFileContent []HTTPFileContent, config *Config) (
 }
 
 func removeComments(httpFileContent []HTTPFileContent) ([]HTTPFileContent, error) {
-	// List of exceptions where the comment should be preserved.
-	exceptions := []string{
-		"// @prompt",
-		"// @name",
-		"// @note",
-		"// @no-redirect",
-		"// @no-cookie-jar",
-	}
-
 	for i, file := range httpFileContent {
 		lines := strings.Split(file.RawContent, "\n")
 		var filteredLines []string
+		// preamble is true until the request line of the block, inScript within a `< {% %}` there
+		preamble, inScript := true, false
 
 		for _, line := range lines {
 			trimmed := strings.TrimSpace(line)
-			if strings.HasPrefix(trimmed, "//") {
-				// Check if the line starts with any of the exceptions.
-				exceptionFound := false
-				for _, exception := range exceptions {
-					if strings.HasPrefix(trimmed, exception) {
-						exceptionFound = true
-						break
-					}
-				}
-				// Skip the line only if it's a comment and doesn't match an exception.
-				if !exceptionFound {
+			switch {
+			case strings.HasPrefix(line, "###"):
+				preamble, inScript = true, false
+			case inScript:
+				inScript = !strings.Contains(trimmed, "%}")
+			case strings.HasPrefix(trimmed, "//"):
+				// Directives like `// @name login` or `// @assert status == 200` before the
+				// request line are preserved, newHTTPBlock reads them
+				if _, _, isDirective := parseDirectiveLine(trimmed); !isDirective || !preamble {
 					continue
 				}
+			case !preamble, trimmed == "", strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "@"):
+			case strings.HasPrefix(trimmed, "<") && strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(trimmed, "<")), "{%"):
+				_, code, _ := strings.Cut(trimmed, "{%")
+				inScript = !strings.Contains(code, "%}")
+			default:
+				preamble = false
 			}
 			filteredLines = append(filteredLines, line)
 		}
@@ -405,8 +466,6 @@ func removeComments(httpFileContent []HTTPFileContent) ([]HTTPFileContent, error
 func getGlobalVariables(httpFileContent []HTTPFileContent) ([]HTTPFileContent, error) {
 	// Regular expression to capture lines like: @variable = value
 	reVar := regexp.MustCompile(`^\s*@(\w+)\s*=\s*(.+)$`)
-	// Regular expression to capture placeholders like: {{variable}} or {{variable/}}
-	rePlaceholder := regexp.MustCompile(`\{\{([^}]+)\}\}`)
 
 	// Process each file
 	for i, file := range httpFileContent {
@@ -431,23 +490,7 @@ func getGlobalVariables(httpFileContent []HTTPFileContent) ([]HTTPFileContent, e
 		processedContent := strings.Join(filteredLines, "\n")
 
 		// Replace all placeholders with their corresponding global variable values.
-		processedContent = rePlaceholder.ReplaceAllStringFunc(processedContent, func(match string) string {
-			// Extract the inner variable name from {{...}} and trim spaces.
-			inner := strings.TrimSpace(match[2 : len(match)-2])
-			// Direct match
-			if val, ok := globals[inner]; ok {
-				return val
-			}
-			// If the placeholder ends with a slash, try trimming it and match again.
-			if strings.HasSuffix(inner, "/") {
-				trimmed := strings.TrimSuffix(inner, "/")
-				if val, ok := globals[trimmed]; ok {
-					return val
-				}
-			}
-			// If not found, return the original placeholder unchanged.
-			return match
-		})
+		processedContent = replaceFileVariables(processedContent, globals, file.EnvironmentVariables)
 
 		// Update the HTTPFileContent with the processed content and global variables.
 		httpFileContent[i].RawContent = processedContent
@@ -457,6 +500,37 @@ func getGlobalVariables(httpFileContent []HTTPFileContent) ([]HTTPFileContent, e
 	return httpFileContent, nil
 }
 
+// Placeholders like {{variable}} or {{variable/}}
+var rePlaceholder = regexp.MustCompile(`\{\{[^}]+\}\}`)
+
+// replaceFileVariables replaces placeholders like {{variable}} or {{variable/}} with
+// the file's own variables, or the environment ones when the file doesn't define them
+func replaceFileVariables(text string, globals map[string]string, environment map[string]string) string {
+	return rePlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+		// Extract the inner variable name from {{...}} and trim spaces.
+		inner := strings.TrimSpace(match[2 : len(match)-2])
+		// Direct match, the file's own variables win over the environment ones
+		if val, ok := globals[inner]; ok {
+			return val
+		}
+		if val, ok := environment[inner]; ok {
+			return val
+		}
+		// If the placeholder ends with a slash, try trimming it and match again.
+		if strings.HasSuffix(inner, "/") {
+			trimmed := strings.TrimSuffix(inner, "/")
+			if val, ok := globals[trimmed]; ok {
+				return val
+			}
+			if val, ok := environment[trimmed]; ok {
+				return val
+			}
+		}
+		// If not found, return the original placeholder unchanged.
+		return match
+	})
+}
+
 //	[]HTTPFileContent{
 //			{Content: "GET {{baseURL/}}/path HTTP/1.1", FilePath: "/path/to/File1"},
 //			{Content: "GET {{baseURL/}}/path HTTP/1.1", FilePath: "/path/to/File2"},
@@ -572,13 +646,9 @@ func parseBlocks(httpFileContent []HTTPFileContent) ([]HTTPFileContent, error) {
 			// Check if the line starts with the delimiter
 			if strings.HasPrefix(line, "###") {
 				// We found a delimiter, store the current block if not empty
-				blockContent := strings.TrimSpace(currentBlock.String())
-				if blockContent != "" {
-					blocks = append(blocks, HTTPBlock{
-						ID:                blockID,
-						BlockContent:      blockContent,
-						CommentIdentifier: currentComment,
-					})
+				block := newHTTPBlock(blockID, currentBlock.String(), currentComment)
+				if block.BlockContent != "" {
+					blocks = append(blocks, block)
 					blockID++
 				}
 				// Reset for the next block
@@ -599,13 +669,9 @@ func parseBlocks(httpFileContent []HTTPFileContent) ([]HTTPFileContent, error) {
 		}
 
 		// Don't forget to add the last block if it's not empty
-		blockContent := strings.TrimSpace(currentBlock.String())
-		if blockContent != "" {
-			blocks = append(blocks, HTTPBlock{
-				ID:                blockID,
-				BlockContent:      blockContent,
-				CommentIdentifier: currentComment,
-			})
+		block := newHTTPBlock(blockID, currentBlock.String(), currentComment)
+		if block.BlockContent != "" {
+			blocks = append(blocks, block)
 		}
 
 		// Check if any blocks were found
@@ -615,12 +681,130 @@ func parseBlocks(httpFileContent []HTTPFileContent) ([]HTTPFileContent, error) {
 
 		// Update the Blocks field in the httpFileContent
 		httpFileContent[i].Blocks = blocks
+		httpFileContent[i].Parallel = blocks[0].Parallel
 	}
 
 	return httpFileContent, nil
 }
 
-func stringToHTTPStruct(requestString string) (HTTPRequest, error) {
+// newHTTPBlock builds a block from its raw content, moving the directive lines
+// written above the request line (`// @name login`) into Name and Directives
+func newHTTPBlock(id int, content string, comment string) HTTPBlock {
+	block := HTTPBlock{
+		ID:                id,
+		CommentIdentifier: comment,
+	}
+
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	k := 0
+	for ; k < len(lines); k++ {
+		trimmed := strings.TrimSpace(lines[k])
+		if trimmed == "" {
+			continue
+		}
+		if script, next, ok := cutScript(lines, k, "<"); ok {
+			block.PreRequestScript = joinScripts(block.PreRequestScript, script)
+			k = next - 1
+			continue
+		}
+		name, value, ok := parseDirectiveLine(trimmed)
+		if !ok {
+			break
+		}
+		switch name {
+		case "name":
+			block.Name = value
+		case "no-redirect":
+			block.NoRedirect = true
+		case "no-cookie-jar":
+			block.NoCookieJar = true
+		case "note":
+			block.Note = true
+		case "prompt":
+			promptName, description, _ := strings.Cut(value, " ")
+			if promptName != "" {
+				block.Prompts = append(block.Prompts, BlockPrompt{Name: promptName, Description: strings.TrimSpace(description)})
+			}
+		case "assert":
+			block.Assertions = append(block.Assertions, parseAssertion(value))
+		case "schema":
+			block.Schema = value
+		case "snapshot-ignore":
+			block.SnapshotIgnore = append(block.SnapshotIgnore, strings.FieldsFunc(value, func(r rune) bool {
+				return r == ',' || r == ' '
+			})...)
+		case "depends-on":
+			block.DependsOn = append(block.DependsOn, strings.FieldsFunc(value, func(r rune) bool {
+				return r == ',' || r == ' '
+			})...)
+		case "parallel":
+			block.Parallel = true
+		case "retry":
+			block.Retry = parseRetryDirective(value)
+		case "show":
+			parts := strings.FieldsFunc(value, func(r rune) bool {
+				return r == ',' || r == ' '
+			})
+			block.ShowBody = len(parts) == 0
+			for _, part := range parts {
+				block.ShowBody = block.ShowBody || part == "body"
+				block.ShowHeaders = block.ShowHeaders || part == "headers"
+			}
+		}
+		block.Directives = append(block.Directives, BlockDirective{Name: name, Value: value})
+	}
+
+	// `> {% %}` response handlers aren't part of the request body
+	var rest []string
+	for i := k; i < len(lines); i++ {
+		if script, next, ok := cutScript(lines, i, ">"); ok {
+			block.ResponseHandler = joinScripts(block.ResponseHandler, script)
+			i = next - 1
+			continue
+		}
+		rest = append(rest, lines[i])
+	}
+
+	block.BlockContent = strings.TrimSpace(strings.Join(rest, "\n"))
+	return block
+}
+
+func joinScripts(scripts ...string) string {
+	var parts []string
+	for _, script := range scripts {
+		if script != "" {
+			parts = append(parts, script)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// parseDirectiveLine splits `// @name login` or `# @name login` into "name" and "login"
+func parseDirectiveLine(line string) (string, string, bool) {
+	var rest string
+	switch {
+	case strings.HasPrefix(line, "//"):
+		rest = strings.TrimSpace(strings.TrimPrefix(line, "//"))
+	case strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "###"):
+		rest = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+	default:
+		return "", "", false
+	}
+	if !strings.HasPrefix(rest, "@") {
+		return "", "", false
+	}
+	rest = strings.TrimPrefix(rest, "@")
+
+	name, value, _ := strings.Cut(rest, " ")
+	if name == "" {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(value), true
+}
+
+// stringToHTTPStruct parses a request block, filePath is the .http file it comes
+// from and is used to find the files referenced by `< ./file` bodies
+func stringToHTTPStruct(requestString string, filePath string) (HTTPRequest, error) {
 	// Initialize default values for the request object
 	requestObject := HTTPRequest{
 		Method:      "GET",
@@ -708,16 +892,43 @@ func stringToHTTPStruct(requestString string) (HTTPRequest, error) {
 		headerIndex++
 	}
 
+	// GraphQL blocks are sent as JSON, the header is only a marker
+	for key, value := range requestObject.Headers {
+		if strings.EqualFold(key, graphQLRequestTypeHeader) && strings.EqualFold(value, "GraphQL") {
+			delete(requestObject.Headers, key)
+			requestObject.GraphQL = true
+			if headerValue(requestObject.Headers, "Content-Type") == "" {
+				requestObject.Headers["Content-Type"] = "application/json"
+			}
+		}
+	}
+
 	// Extract body if present
 	if bodyStartIndex > 0 && bodyStartIndex < len(lines) {
 		bodyLines := lines[bodyStartIndex:]
 		body := strings.Join(bodyLines, "\n") // Use consistent line endings in body
 
-		if body != "" {
+		if bodyFile, withVariables, ok := parseBodyFileLine(body); ok {
+			// `< ./file` sends the file as the body, `<@ ./file` also replaces its variables
+			if !filepath.IsAbs(bodyFile) {
+				bodyFile = filepath.Join(filepath.Dir(filePath), bodyFile)
+			}
+			if _, err := checkPathExists(bodyFile); err != nil {
+				return requestObject, fmt.Errorf("body file error: %w", err)
+			}
+			requestObject.BodyFile = bodyFile
+			requestObject.BodyFileVariables = withVariables
+		} else if multipart, err := isMultipartFormData(headerValue(requestObject.Headers, "Content-Type")); err != nil {
+			return requestObject, err
+		} else if multipart {
+			// Content-Length is only known once the file parts are read
+			requestObject.Body = body
+			requestObject.Multipart = true
+		} else if body != "" {
 			requestObject.Body = body
 
-			// Set Content-Length if missing
-			if _, exists := requestObject.Headers["Content-Length"]; !exists {
+			// Set Content-Length if missing, GraphQL bodies change size when wrapped
+			if _, exists := requestObject.Headers["Content-Length"]; !exists && !requestObject.GraphQL {
 				requestObject.Headers["Content-Length"] = strconv.Itoa(len(body))
 			}
 		}
@@ -735,6 +946,21 @@ func stringToHTTPStruct(requestString string) (HTTPRequest, error) {
 	return requestObject, nil
 }
 
+// parseBodyFileLine recognises a body made of a single `< ./file` or `<@ ./file` line
+func parseBodyFileLine(body string) (string, bool, bool) {
+	line := strings.TrimSpace(body)
+	if strings.Contains(line, "\n") {
+		return "", false, false
+	}
+	switch {
+	case strings.HasPrefix(line, "<@ "):
+		return strings.TrimSpace(line[3:]), true, true
+	case strings.HasPrefix(line, "< "):
+		return strings.TrimSpace(line[2:]), false, true
+	}
+	return "", false, false
+}
+
 // =====
 func parseHTTPBlockResponses(httpFileContent []HTTPFileContent, config *Config) ([]HTTPFileContent, error) {
 	// 1. It iterates through each HTTPFileContent in the provided slice
@@ -787,6 +1013,7 @@ func parseHTTPBlockResponses(httpFileContent []HTTPFileContent, config *Config)
 					}
 					httpFileContent[i].Blocks[j].ExpectedResponse = expectedResponse
 					httpFileContent[i].Blocks[j].ExpectedResponseString = nextBlock.BlockContent
+					httpFileContent[i].Blocks[j].ExpectedResponseBody = response.ResponseBody
 					if verbose {
 						log.Printf("Found response for request %d in file %s", j, httpFileContent[i].FilePath)
 					}
@@ -811,15 +1038,23 @@ func parseHTTPResponse(responseContent string) (*HTTPResponse, error) {
 	}
 	firstLine := strings.TrimSpace(lines[0])
 	parts := strings.SplitN(firstLine, " ", 3)
-	if len(parts) < 3 {
+	if len(parts) < 2 {
 		return nil, fmt.Errorf("invalid status line format: %s", firstLine)
 	}
 	protocol := parts[0]
 	statusCode, err := strconv.Atoi(parts[1])
+	if class, ok := statusClass(parts[1]); ok {
+		// HTTP/1.1 2xx, any status of the class
+		statusCode, err = class*100, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("invalid status code: %s", parts[1])
 	}
-	statusText := string(parts[1]) + " " + parts[2]
+	// The reason phrase is optional
+	statusText := parts[1]
+	if len(parts) == 3 {
+		statusText += " " + parts[2]
+	}
 	headers := make(map[string]string)
 	bodyStartIndex := 1 // Default to start after status line
 
@@ -3,6 +3,7 @@ package main
 import (
 	testcases "lazyrequests/http_folder"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -18,6 +19,11 @@ func TestProcessHTTPFiles(t *testing.T) {
 		{"test_3_parse_blocks.http", testcases.Test_3_parse_blocks},
 		{"test_4_parse_requests.http", testcases.Test_4_parse_requests},
 		{"test_5_parse_responses.http", testcases.Test_5_parse_responses},
+		{"test_6_request_variables.http", testcases.Test_6_request_variables},
+		{"test_7_body_files.http", testcases.Test_7_body_files},
+		{"test_8_multipart.http", testcases.Test_8_multipart},
+		{"test_9_assertions.http", testcases.Test_9_assertions},
+		{"test_10_scripts.http", testcases.Test_10_scripts},
 	}
 
 	// Loop through all test files
@@ -32,7 +38,7 @@ func TestProcessHTTPFiles(t *testing.T) {
 				HTTPFolderPath:  "",
 				ExcludeFile:     "",
 				ExcludeFolder:   "",
-				WaitRequestTime: 1000, // Default 1 second in milliseconds
+				SleepTime:       1000, // Default 1 second in milliseconds
 				Verbose:         false,
 			}
 
@@ -85,6 +91,13 @@ func TestProcessHTTPFiles(t *testing.T) {
 					return
 				}
 
+				Name := block.Name
+				Name_Expected := testCase.httpExpected[i].Name
+				if Name != Name_Expected {
+					t.Errorf("Incorrect NAME [%d].\nexpected: %s\nGot:      %s", i, Name_Expected, Name)
+					return
+				}
+
 				// ====
 				// Request body
 				// ====
@@ -95,6 +108,47 @@ func TestProcessHTTPFiles(t *testing.T) {
 					t.Errorf("Incorrect Body [%d].\nexpected: %x\nGot:      %x", i, []byte(expectedBody), []byte(bodyString))
 					return
 				}
+				bodyFile := block.Request.BodyFile
+				bodyFile_Expected := testCase.httpExpected[i].BodyFile
+				if !strings.HasSuffix(filepath.ToSlash(bodyFile), bodyFile_Expected) || (bodyFile == "") != (bodyFile_Expected == "") {
+					t.Errorf("Incorrect BodyFile [%d].\nexpected: %s\nGot:      %s", i, bodyFile_Expected, bodyFile)
+					return
+				}
+				multipartBody_Expected := testCase.httpExpected[i].MultipartBody
+				if block.Request.Multipart != (multipartBody_Expected != "") {
+					t.Errorf("Incorrect Multipart [%d]. expected: %v, Got: %v", i, multipartBody_Expected != "", block.Request.Multipart)
+					return
+				}
+				if block.Request.Multipart {
+					multipartBody, err := buildMultipartBody(block.Request.Body, filepath.Dir(filepath_original))
+					if err != nil {
+						t.Errorf("got error on function buildMultipartBody: %v", err)
+						return
+					}
+					if string(multipartBody) != multipartBody_Expected {
+						t.Errorf("Incorrect multipart body [%d].\nexpected: %x\nGot:      %x", i, []byte(multipartBody_Expected), multipartBody)
+						return
+					}
+				}
+				assertions_Expected := testCase.httpExpected[i].Assertions
+				if len(block.Assertions) != len(assertions_Expected) {
+					t.Errorf("Incorrect Assertions [%d].\nexpected: %v\nGot:      %+v", i, assertions_Expected, block.Assertions)
+					return
+				}
+				for a, assertion := range block.Assertions {
+					if assertion.Text != assertions_Expected[a] || assertion.Err != nil {
+						t.Errorf("Incorrect Assertion [%d][%d].\nexpected: %s\nGot:      %s (%v)", i, a, assertions_Expected[a], assertion.Text, assertion.Err)
+						return
+					}
+				}
+				if block.PreRequestScript != testCase.httpExpected[i].PreRequestScript {
+					t.Errorf("Incorrect PreRequestScript [%d].\nexpected: %q\nGot:      %q", i, testCase.httpExpected[i].PreRequestScript, block.PreRequestScript)
+					return
+				}
+				if block.ResponseHandler != testCase.httpExpected[i].ResponseHandler {
+					t.Errorf("Incorrect ResponseHandler [%d].\nexpected: %q\nGot:      %q", i, testCase.httpExpected[i].ResponseHandler, block.ResponseHandler)
+					return
+				}
 				//fmt.Printf("Hex (spaced):\r\n% x\n", []byte(bodyString))
 				got := block.Request.Headers["User-Agent"]
 				if got != "" {
@@ -124,3 +178,34 @@ func TestProcessHTTPFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHTTPBlockDirectives(t *testing.T) {
+	content := "// @name deleteUser\n# @no-redirect\n// @no-cookie-jar\n// @note\n// @show headers\nDELETE http://localhost:8080/users/1 HTTP/1.1"
+	block := newHTTPBlock(1, content, "delete the user")
+
+	if block.BlockContent != "DELETE http://localhost:8080/users/1 HTTP/1.1" {
+		t.Errorf("Directives should be removed from the block: %q", block.BlockContent)
+	}
+	if block.Name != "deleteUser" || !block.NoRedirect || !block.NoCookieJar || !block.Note || block.ShowBody || !block.ShowHeaders {
+		t.Errorf("Incorrect directives: %+v", block)
+	}
+	if len(block.Directives) != 5 {
+		t.Errorf("expected 5 directives, Got: %v", block.Directives)
+	}
+	if block := newHTTPBlock(2, "// @show\nGET http://localhost:8080/users HTTP/1.1", ""); !block.ShowBody || block.ShowHeaders {
+		t.Errorf("`// @show` alone shows the body: %+v", block)
+	}
+}
+
+func TestRemoveCommentsKeepsDirectivesBeforeTheRequestLine(t *testing.T) {
+	content := "// @name createUser\n< {%\n  // comments in scripts stay\n%}\n// @assert status == 201\nPOST http://localhost:8080/users HTTP/1.1\nContent-Type: application/json\n// @foo\n\n{\"name\": \"Ada\"}\n// @bar\n### second\n// @name listUsers\nGET http://localhost:8080/users HTTP/1.1"
+	expected := "// @name createUser\n< {%\n  // comments in scripts stay\n%}\n// @assert status == 201\nPOST http://localhost:8080/users HTTP/1.1\nContent-Type: application/json\n\n{\"name\": \"Ada\"}\n### second\n// @name listUsers\nGET http://localhost:8080/users HTTP/1.1"
+
+	httpFileContent, err := removeComments([]HTTPFileContent{{RawContent: content}})
+	if err != nil {
+		t.Fatalf("got error on function removeComments: %v", err)
+	}
+	if httpFileContent[0].RawContent != expected {
+		t.Errorf("Incorrect content. expected: %q, Got: %q", expected, httpFileContent[0].RawContent)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BlockPrompt is a `// @prompt email Enter the user email` directive, its value is
+// asked in the terminal before the block is sent and replaces {{email}}
+type BlockPrompt struct {
+	Name        string
+	Description string
+}
+
+// Prefix of the environment variables that answer prompts, LAZYREQUESTS_PROMPT_EMAIL answers {{email}}
+const promptEnvPrefix = "LAZYREQUESTS_PROMPT_"
+
+var (
+	// Answers typed during the watch session, asked again after the `forget` command
+	promptAnswersMu sync.Mutex
+	promptAnswers   = make(map[string]string)
+	promptAskingMu  sync.Mutex
+)
+
+// promptFlag collects the repeated `--prompt name=value` flags
+type promptFlag map[string]string
+
+func (p promptFlag) String() string {
+	var pairs []string
+	for name, value := range p {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p promptFlag) Set(value string) error {
+	name, answer, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("prompt must be name=value: %s", value)
+	}
+	p[strings.TrimSpace(name)] = answer
+	return nil
+}
+
+// resolvePromptVariables returns a copy of req with the prompt variables of the block
+// replaced, the values come from --prompt, the environment, a previous answer or the terminal
+func resolvePromptVariables(ctx context.Context, req HTTPRequest, block HTTPBlock, config *Config) (HTTPRequest, error) {
+	if len(block.Prompts) == 0 {
+		return req, nil
+	}
+
+	values := make(map[string]string, len(block.Prompts))
+	for _, prompt := range block.Prompts {
+		value, err := promptValue(ctx, prompt, config)
+		if err != nil {
+			return req, err
+		}
+		values[prompt.Name] = value
+	}
+
+	resolved := mapRequestText(req, func(text string) string {
+		return rePlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+			if value, ok := values[strings.TrimSpace(match[2:len(match)-2])]; ok {
+				return value
+			}
+			return match
+		})
+	})
+	return resolved, nil
+}
+
+func promptValue(ctx context.Context, prompt BlockPrompt, config *Config) (string, error) {
+	if value, ok := config.PromptValues[prompt.Name]; ok {
+		return value, nil
+	}
+	if value, ok := os.LookupEnv(promptEnvPrefix + strings.ToUpper(prompt.Name)); ok {
+		return value, nil
+	}
+
+	// One prompt at a time, parallel blocks waiting for the same prompt get the first answer
+	promptAskingMu.Lock()
+	defer promptAskingMu.Unlock()
+	promptAnswersMu.Lock()
+	value, ok := promptAnswers[prompt.Name]
+	promptAnswersMu.Unlock()
+	if ok {
+		return value, nil
+	}
+
+	description := prompt.Description
+	if description == "" {
+		description = prompt.Name
+	}
+	masked := isSecretPrompt(prompt.Name)
+	if masked {
+		setTerminalEcho(false)
+	}
+	value, err := terminal.ask(ctx, fmt.Sprintf("%s%s: %s", C_Yellow, description, C_Reset))
+	if masked {
+		setTerminalEcho(true)
+		fmt.Println()
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err != nil {
+		return "", fmt.Errorf("no value for prompt %s, pass --prompt %s=value or set %s%s", prompt.Name, prompt.Name, promptEnvPrefix, strings.ToUpper(prompt.Name))
+	}
+
+	promptAnswersMu.Lock()
+	promptAnswers[prompt.Name] = value
+	promptAnswersMu.Unlock()
+	return value, nil
+}
+
+// secretPromptValues returns the values of the block's secret prompts once they were resolved,
+// so they can be kept out of the history
+func secretPromptValues(block HTTPBlock, config *Config) []string {
+	var secrets []string
+	for _, prompt := range block.Prompts {
+		if !isSecretPrompt(prompt.Name) {
+			continue
+		}
+		value, ok := config.PromptValues[prompt.Name]
+		if !ok {
+			value, ok = os.LookupEnv(promptEnvPrefix + strings.ToUpper(prompt.Name))
+		}
+		if !ok {
+			promptAnswersMu.Lock()
+			value, ok = promptAnswers[prompt.Name]
+			promptAnswersMu.Unlock()
+		}
+		if ok && value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// forgetPromptAnswers makes the prompts ask again, all of them when no name is given
+func forgetPromptAnswers(names ...string) {
+	promptAnswersMu.Lock()
+	defer promptAnswersMu.Unlock()
+	if len(names) == 0 {
+		promptAnswers = make(map[string]string)
+		return
+	}
+	for _, name := range names {
+		delete(promptAnswers, name)
+	}
+}
+
+// isSecretPrompt tells if the answer shouldn't be shown while typed
+func isSecretPrompt(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range []string{"password", "passwd", "secret", "token"} {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// setTerminalEcho shows or hides what is typed in the terminal
+func setTerminalEcho(on bool) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	mode := "echo"
+	if !on {
+		mode = "-echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = os.Stdin
+	cmd.Run()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResolvePromptVariables(t *testing.T) {
+	t.Setenv(promptEnvPrefix+"EMAIL", "env@example.com")
+	forgetPromptAnswers()
+	promptAnswers["code"] = "1234"
+	defer forgetPromptAnswers()
+
+	block := HTTPBlock{Prompts: []BlockPrompt{
+		{Name: "email", Description: "Enter the user email"},
+		{Name: "password"},
+		{Name: "code"},
+	}}
+	req := HTTPRequest{
+		Url:     "http://localhost:8080/reset?email={{email}}",
+		Headers: map[string]string{},
+		Body:    `{"password": "{{password}}", "code": "{{code}}", "other": "{{other}}"}`,
+	}
+	config := &Config{PromptValues: map[string]string{"password": "flag-secret"}}
+
+	resolved, err := resolvePromptVariables(context.Background(), req, block, config)
+	if err != nil {
+		t.Fatalf("got error on function resolvePromptVariables: %v", err)
+	}
+	if resolved.Url != "http://localhost:8080/reset?email=env@example.com" {
+		t.Errorf("Incorrect URL: %s", resolved.Url)
+	}
+	expectedBody := `{"password": "flag-secret", "code": "1234", "other": "{{other}}"}`
+	if resolved.Body != expectedBody {
+		t.Errorf("Incorrect Body.\nexpected: %s\nGot:      %s", expectedBody, resolved.Body)
+	}
+}
+
+func TestIsSecretPrompt(t *testing.T) {
+	tests := map[string]bool{
+		"password":    true,
+		"newPassword": true,
+		"apiSecret":   true,
+		"TOKEN":       true,
+		"email":       false,
+	}
+	for name, expected := range tests {
+		if got := isSecretPrompt(name); got != expected {
+			t.Errorf("isSecretPrompt(%s) expected: %v, Got: %v", name, expected, got)
+		}
+	}
+}
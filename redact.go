@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Headers whose values are kept out of the history and the HTML report
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"}
+
+const redactedValue = "[redacted]"
+
+// redactSecrets hides the answers of secret prompts, like `// @prompt password`, in text.
+// It tells if one was found.
+func redactSecrets(text string, secrets []string) (string, bool) {
+	found := false
+	for _, secret := range secrets {
+		if secret != "" && strings.Contains(text, secret) {
+			text = strings.ReplaceAll(text, secret, redactedValue)
+			found = true
+		}
+	}
+	return text, found
+}
+
+// redactHeaders returns a copy of the headers without the values of sensitiveHeaders and
+// secret prompt answers, with the names of the headers that were changed
+func redactHeaders(headers http.Header, secrets []string) (http.Header, []string) {
+	if headers == nil {
+		return nil, nil
+	}
+	// The headers are shared with the other reporters
+	headers = headers.Clone()
+	var redacted []string
+	for name, values := range headers {
+		found := false
+		for i, value := range values {
+			var secret bool
+			values[i], secret = redactSecrets(value, secrets)
+			found = found || secret
+		}
+		for _, sensitive := range sensitiveHeaders {
+			if strings.EqualFold(name, sensitive) {
+				headers[name] = []string{redactedValue}
+				found = true
+			}
+		}
+		if found {
+			redacted = append(redacted, name)
+		}
+	}
+	sort.Strings(redacted)
+	return headers, redacted
+}
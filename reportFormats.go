@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// JUnit XML, one testsuite per .http file and one testcase per block
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func writeJUnitReport(w io.Writer, summary runSummary, results []blockResult) error {
+	report := junitTestSuites{
+		Name:     "lazyrequests",
+		Tests:    len(results),
+		Failures: summary.Failed,
+		Errors:   summary.Errored,
+		Skipped:  summary.Skipped,
+		Time:     junitSeconds(summary.Duration),
+	}
+
+	// Index of the suite of each .http file in report.Suites
+	suiteIndex := make(map[string]int)
+	suiteDurations := make(map[string]time.Duration)
+	for _, result := range results {
+		suiteName := filepath.Base(result.File)
+		index, ok := suiteIndex[suiteName]
+		if !ok {
+			index = len(report.Suites)
+			suiteIndex[suiteName] = index
+			report.Suites = append(report.Suites, junitTestSuite{Name: suiteName, Timestamp: time.Now().Format(time.RFC3339)})
+		}
+		suite := &report.Suites[index]
+
+		testCase := junitTestCase{Name: result.name(), ClassName: suiteName, Time: junitSeconds(result.Duration)}
+		message := strings.Join(result.messages(), "\n")
+		switch result.Outcome {
+		case outcomeFailed:
+			testCase.Failure = &junitMessage{Message: firstLine(message), Type: "assertion", Text: message}
+			suite.Failures++
+		case outcomeErrored:
+			testCase.Error = &junitMessage{Message: firstLine(message), Type: "error", Text: message}
+			suite.Errors++
+		case outcomeSkipped:
+			testCase.Skipped = &junitMessage{Message: result.skipReason()}
+			suite.Skipped++
+		}
+		if len(result.Warnings) > 0 {
+			testCase.SystemOut = strings.Join(result.Warnings, "\n")
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		suiteDurations[suiteName] += result.Duration
+		suite.Time = junitSeconds(suiteDurations[suiteName])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
+
+// writeTAPReport writes the results as TAP version 13, failures in the YAML block of each test
+func writeTAPReport(w io.Writer, summary runSummary, results []blockResult) error {
+	var tap strings.Builder
+	tap.WriteString("TAP version 13\n")
+	fmt.Fprintf(&tap, "1..%d\n", len(results))
+
+	for i, result := range results {
+		// # starts a directive in TAP, so it can't be part of the name
+		name := strings.ReplaceAll(result.name(), "#", "\\#")
+		switch result.Outcome {
+		case outcomePassed:
+			fmt.Fprintf(&tap, "ok %d - %s\n", i+1, name)
+		case outcomeSkipped:
+			fmt.Fprintf(&tap, "ok %d - %s # SKIP %s\n", i+1, name, result.skipReason())
+		default:
+			fmt.Fprintf(&tap, "not ok %d - %s\n", i+1, name)
+		}
+
+		if result.Outcome == outcomeSkipped {
+			continue
+		}
+		tap.WriteString("  ---\n")
+		fmt.Fprintf(&tap, "  file: %s\n", yamlString(filepath.Base(result.File)))
+		fmt.Fprintf(&tap, "  request: %s\n", yamlString(result.Method+" "+result.URL))
+		if result.Status != "" {
+			fmt.Fprintf(&tap, "  status: %s\n", yamlString(result.Status))
+		}
+		fmt.Fprintf(&tap, "  duration_ms: %d\n", result.Duration.Milliseconds())
+		if messages := result.messages(); len(messages) > 0 {
+			tap.WriteString("  failures:\n")
+			for _, message := range messages {
+				fmt.Fprintf(&tap, "    - %s\n", yamlString(message))
+			}
+		}
+		tap.WriteString("  ...\n")
+	}
+
+	_, err := io.WriteString(w, tap.String())
+	return err
+}
+
+// yamlString quotes a YAML scalar, JSON strings are valid YAML
+func yamlString(text string) string {
+	quoted, _ := json.Marshal(text)
+	return string(quoted)
+}
+
+type jsonReport struct {
+	Summary jsonSummary       `json:"summary"`
+	Results []jsonBlockResult `json:"results"`
+}
+
+type jsonSummary struct {
+	Passed     int   `json:"passed"`
+	Failed     int   `json:"failed"`
+	Errored    int   `json:"errored"`
+	Skipped    int   `json:"skipped"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+type jsonBlockResult struct {
+	Name       string                `json:"name"`
+	File       string                `json:"file"`
+	Block      int                   `json:"block"`
+	Method     string                `json:"method"`
+	URL        string                `json:"url"`
+	Status     string                `json:"status,omitempty"`
+	Outcome    string                `json:"outcome"`
+	DurationMs int64                 `json:"durationMs"`
+	Attempts   int                   `json:"attempts,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	Failures   []jsonFailure         `json:"failures,omitempty"`
+	Assertions []jsonAssertionResult `json:"assertions,omitempty"`
+	Warnings   []string              `json:"warnings,omitempty"`
+}
+
+type jsonFailure struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected,omitempty"`
+	Got      string `json:"got,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+type jsonAssertionResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Got    string `json:"got,omitempty"`
+}
+
+func writeJSONReport(w io.Writer, summary runSummary, results []blockResult) error {
+	report := jsonReport{
+		Summary: jsonSummary{
+			Passed:     summary.Passed,
+			Failed:     summary.Failed,
+			Errored:    summary.Errored,
+			Skipped:    summary.Skipped,
+			DurationMs: summary.Duration.Milliseconds(),
+		},
+		Results: []jsonBlockResult{},
+	}
+
+	for _, result := range results {
+		entry := jsonBlockResult{
+			Name:       result.name(),
+			File:       result.File,
+			Block:      result.Block.ID,
+			Method:     result.Method,
+			URL:        result.URL,
+			Status:     result.Status,
+			Outcome:    result.Outcome,
+			DurationMs: result.Duration.Milliseconds(),
+			Warnings:   result.Warnings,
+		}
+		if result.Attempts > 1 {
+			entry.Attempts = result.Attempts
+		}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+		}
+		for _, failure := range result.Failures {
+			entry.Failures = append(entry.Failures, jsonFailure(failure))
+		}
+		for _, assertion := range result.Assertions {
+			entry.Assertions = append(entry.Assertions, jsonAssertionResult(assertion))
+		}
+		report.Results = append(report.Results, entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
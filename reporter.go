@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Outcomes of a block, see runSummary
+const (
+	outcomePassed  = "passed"
+	outcomeFailed  = "failed"
+	outcomeErrored = "errored"
+	outcomeSkipped = "skipped"
+)
+
+// blockResult is what happened when a block was sent
+type blockResult struct {
+	File          string // .http file of the block
+	Block         HTTPBlock
+	Method        string
+	URL           string
+	Status        string // empty when no response was received
+	Outcome       string
+	Duration      time.Duration // of the last attempt
+	Attempts      int           // requests sent, more than one when it was retried
+	Failures      []assertionFailure
+	Assertions    []assertionResult // `@assert` directives and client.test of the scripts
+	SnapshotDiff  []string
+	SnapshotSaved bool
+	Warnings      []string // variables that couldn't be resolved...
+	Secrets       []string // answers of secret prompts, kept out of the history
+	Err           error    // why an errored block couldn't be sent
+
+	// What was sent and received, for the HTML report and the history
+	RequestHeaders  http.Header
+	RequestBody     string
+	RequestBodyFile string // streamed `< ./file` bodies aren't loaded in RequestBody
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+}
+
+// attemptsNote is appended to the result line of a retried block
+func (r blockResult) attemptsNote() string {
+	if r.Attempts <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" %s(%d attempts)%s", C_Gray, r.Attempts, C_Reset)
+}
+
+// name is the test case name of the block: its comment, its `// @name` or the request
+func (r blockResult) name() string {
+	if r.Block.CommentIdentifier != "" {
+		return r.Block.CommentIdentifier
+	}
+	if r.Block.Name != "" {
+		return r.Block.Name
+	}
+	return r.Method + " " + r.URL
+}
+
+// messages are the failures of the block as printed in the terminal, without colors
+func (r blockResult) messages() []string {
+	var messages []string
+	if r.Err != nil {
+		messages = append(messages, r.Err.Error())
+	}
+	for _, failure := range r.Failures {
+		messages = append(messages, failureText(failure))
+	}
+	for _, assertion := range r.Assertions {
+		if !assertion.Passed {
+			messages = append(messages, fmt.Sprintf("[X] %s Got: [ %s ]", assertion.Name, assertion.Got))
+		}
+	}
+	return messages
+}
+
+// skipReason is why a skipped block wasn't sent
+func (r blockResult) skipReason() string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	return "not confirmed"
+}
+
+func (s *runSummary) add(result blockResult) {
+	switch result.Outcome {
+	case outcomePassed:
+		s.Passed++
+	case outcomeFailed:
+		s.Failed++
+	case outcomeErrored:
+		s.Errored++
+	case outcomeSkipped:
+		s.Skipped++
+	}
+	if result.SnapshotSaved {
+		s.Snapshots++
+	}
+}
+
+// reporter receives the results of a run, the terminal prints them as they come
+// while the others write a file once the run finished
+type reporter interface {
+	runStarted(run int, start time.Time)
+	blockFinished(result blockResult)
+	runFinished(summary runSummary, results []blockResult) error
+}
+
+// reporterConfig is a `--reporter name` or `--reporter name=file`
+type reporterConfig struct {
+	Name string
+	Path string // empty writes to the terminal
+}
+
+var reporterNames = []string{"terminal", "junit", "tap", "json"}
+
+// listFlag collects repeated, comma separated flags like `--reporter`
+type listFlag []string
+
+func (r *listFlag) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *listFlag) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*r = append(*r, name)
+		}
+	}
+	return nil
+}
+
+// parseReporters validates --reporter and --report-file, only one reporter can write to the terminal.
+// The terminal reporter is kept unless another one writes there instead.
+func parseReporters(names []string, reportFile string) ([]reporterConfig, error) {
+	if len(names) == 0 {
+		names = []string{"terminal"}
+	}
+
+	var reporters []reporterConfig
+	for _, value := range names {
+		name, path, _ := strings.Cut(value, "=")
+		known := false
+		for _, reporterName := range reporterNames {
+			known = known || name == reporterName
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown reporter %q, use one of %s", name, strings.Join(reporterNames, ", "))
+		}
+		if name == "terminal" && path != "" {
+			return nil, fmt.Errorf("the terminal reporter can't write to a file")
+		}
+		reporters = append(reporters, reporterConfig{Name: name, Path: path})
+	}
+
+	if reportFile != "" {
+		var withoutFile []int
+		for i, reporter := range reporters {
+			if reporter.Name != "terminal" && reporter.Path == "" {
+				withoutFile = append(withoutFile, i)
+			}
+		}
+		if len(withoutFile) != 1 {
+			return nil, fmt.Errorf("--report-file needs exactly one junit, tap or json reporter without file, use --reporter name=file for more")
+		}
+		reporters[withoutFile[0]].Path = reportFile
+	}
+
+	toTerminal := 0
+	for _, reporter := range reporters {
+		if reporter.Path == "" {
+			toTerminal++
+		}
+	}
+	if toTerminal > 1 {
+		return nil, fmt.Errorf("only one reporter can write to the terminal, give the others a file with --report-file or --reporter name=file")
+	}
+	if toTerminal == 0 {
+		reporters = append([]reporterConfig{{Name: "terminal"}}, reporters...)
+	}
+	return reporters, nil
+}
+
+// reporters sends the results to every reporter
+type reporters []reporter
+
+func newReporters(config *Config) reporters {
+	configs := config.Reporters
+	if len(configs) == 0 {
+		configs = []reporterConfig{{Name: "terminal"}}
+	}
+
+	var list reporters
+	for _, rc := range configs {
+		switch rc.Name {
+		case "terminal":
+			list = append(list, &terminalReporter{
+				summary:     config.Run,
+				snapshots:   config.SnapshotUpdate,
+				diff:        config.Diff,
+				showBody:    config.ShowBody,
+				showHeaders: config.ShowHeaders,
+				bodyLimit:   config.ShowBodyLimit,
+			})
+		case "junit":
+			list = append(list, &fileReporter{path: rc.Path, write: writeJUnitReport})
+		case "tap":
+			list = append(list, &fileReporter{path: rc.Path, write: writeTAPReport})
+		case "json":
+			list = append(list, &fileReporter{path: rc.Path, write: writeJSONReport})
+		}
+	}
+	if config.HTMLReport != "" {
+		list = append(list, &fileReporter{path: config.HTMLReport, write: writeHTMLReport})
+	}
+	if config.HistoryFile != "" {
+		list = append(list, &historyReporter{path: config.HistoryFile, bodyLimit: config.HistoryBodyLimit})
+	}
+	return list
+}
+
+func (list reporters) runStarted(run int, start time.Time) {
+	for _, r := range list {
+		r.runStarted(run, start)
+	}
+}
+
+func (list reporters) blockFinished(result blockResult) {
+	for _, r := range list {
+		r.blockFinished(result)
+	}
+}
+
+func (list reporters) runFinished(summary runSummary, results []blockResult) {
+	for _, r := range list {
+		if err := r.runFinished(summary, results); err != nil {
+			fmt.Fprintf(os.Stderr, "%sError writing the report: %v%s\n", C_Red, err, C_Reset)
+		}
+	}
+}
+
+// terminalReporter is the coloured output, printed while the blocks are sent
+type terminalReporter struct {
+	summary   bool // print the counts at the end, for `lazyrequests run`
+	snapshots bool // print how many snapshots were written
+	diff      bool // print how the responses changed since the previous run, --diff
+	// --show-body and --show-headers, blocks with `// @show` print them anyway
+	showBody    bool
+	showHeaders bool
+	bodyLimit   int
+	run         int
+}
+
+func (t *terminalReporter) runStarted(run int, start time.Time) {
+	t.run = run
+	fmt.Printf("%s[%d] %s %s\n", C_Underline+C_Bold+C_Cyan, run, start.Format("03:04 PM"), C_Reset)
+}
+
+func (t *terminalReporter) blockFinished(result blockResult) {
+	block := result.Block
+	fileName := filepath.Base(result.File)
+	for _, warning := range result.Warnings {
+		fmt.Printf("%s%s block %d: %s%s\n", C_Yellow, fileName, block.ID, warning, C_Reset)
+	}
+
+	switch result.Outcome {
+	case outcomeSkipped:
+		fmt.Printf("%sskipped %s %s: %s%s\n", C_Yellow, block.Request.Method, block.Request.Url, result.skipReason(), C_Reset)
+	case outcomeErrored:
+		fmt.Printf("%s%s block %d: %v%s%s\n", C_Red, fileName, block.ID, result.Err, C_Reset, result.attemptsNote())
+	case outcomePassed:
+		if block.CommentIdentifier != "" {
+			fmt.Printf("%s%s%s\n", C_Purple, block.CommentIdentifier, C_Reset)
+		}
+		fmt.Printf("%s%-6s %s%-12s %s%3dms %s%s%s%s\n", C_Bold+C_Blue, result.Method, C_Reset+C_Green, result.Status, C_Yellow, result.Duration.Milliseconds(), C_Gray, result.URL, C_Reset, result.attemptsNote())
+	case outcomeFailed:
+		printFailures(block, result.Method, result.URL+result.attemptsNote(), result.Failures)
+	}
+	if result.Status != "" {
+		if t.showHeaders || block.ShowHeaders {
+			printResponseHeaders(result.ResponseHeaders, "    ")
+		}
+		if t.showBody || block.ShowBody {
+			printResponseBody(result.ResponseHeaders, result.ResponseBody, t.bodyLimit, "    ")
+		}
+	}
+	printAssertionResults(result.Assertions)
+	printDiff(result.SnapshotDiff, "    ")
+
+	if t.diff && result.Status != "" {
+		if diff, found := diffWithPreviousRun(t.run, result); found && len(diff) == 0 {
+			fmt.Printf("    %sunchanged%s\n", C_Gray, C_Reset)
+		} else {
+			printDiff(diff, "    ")
+		}
+	}
+}
+
+func (t *terminalReporter) runFinished(summary runSummary, results []blockResult) error {
+	if t.snapshots {
+		fmt.Printf("%s%d snapshots written%s\n", C_Gray, summary.Snapshots, C_Reset)
+	}
+	if summary.Cancelled {
+		fmt.Printf("%scancelled.%s\n", C_Gray, C_Reset)
+		return nil
+	}
+	fmt.Printf("%sdone.%s\n", C_Gray, C_Reset)
+	if t.summary {
+		printSummary(summary)
+	}
+	return nil
+}
+
+// fileReporter writes a report once the run finished, to the terminal when it has no path
+type fileReporter struct {
+	path  string
+	write func(w io.Writer, summary runSummary, results []blockResult) error
+}
+
+func (f *fileReporter) runStarted(run int, start time.Time) {}
+
+func (f *fileReporter) blockFinished(result blockResult) {}
+
+func (f *fileReporter) runFinished(summary runSummary, results []blockResult) error {
+	// Keep the report of the last run that finished
+	if summary.Cancelled {
+		return nil
+	}
+	if f.path == "" {
+		return f.write(os.Stdout, summary, results)
+	}
+	if dir := filepath.Dir(f.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	file, err := os.Create(f.path)
+	if err != nil {
+		return err
+	}
+	if err := f.write(file, summary, results); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
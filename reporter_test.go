@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReporters(t *testing.T) {
+	tests := []struct {
+		name      string
+		names     []string
+		file      string
+		expected  []reporterConfig
+		shouldErr bool
+	}{
+		{"default", nil, "", []reporterConfig{{Name: "terminal"}}, false},
+		{"terminal and junit file", []string{"terminal", "junit"}, "out/junit.xml", []reporterConfig{{Name: "terminal"}, {Name: "junit", Path: "out/junit.xml"}}, false},
+		{"name=file", []string{"terminal", "junit=junit.xml", "json=results.json"}, "", []reporterConfig{{Name: "terminal"}, {Name: "junit", Path: "junit.xml"}, {Name: "json", Path: "results.json"}}, false},
+		{"machine reporter alone", []string{"tap"}, "", []reporterConfig{{Name: "tap"}}, false},
+		{"file keeps the terminal", []string{"junit=junit.xml"}, "", []reporterConfig{{Name: "terminal"}, {Name: "junit", Path: "junit.xml"}}, false},
+		{"report file keeps the terminal", []string{"junit"}, "out.xml", []reporterConfig{{Name: "terminal"}, {Name: "junit", Path: "out.xml"}}, false},
+		{"unknown", []string{"html"}, "", nil, true},
+		{"two to the terminal", []string{"terminal", "junit"}, "", nil, true},
+		{"report file without reporter", nil, "junit.xml", nil, true},
+		{"report file for two reporters", []string{"junit", "tap"}, "report", nil, true},
+		{"terminal to a file", []string{"terminal=out.txt"}, "", nil, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reporters, err := parseReporters(tc.names, tc.file)
+			if (err != nil) != tc.shouldErr {
+				t.Fatalf("expected error: %v, Got: %v", tc.shouldErr, err)
+			}
+			if len(reporters) != len(tc.expected) {
+				t.Fatalf("expected reporters %v, Got: %v", tc.expected, reporters)
+			}
+			for i := range reporters {
+				if reporters[i] != tc.expected[i] {
+					t.Errorf("Incorrect reporter [%d]. expected: %v, Got: %v", i, tc.expected[i], reporters[i])
+				}
+			}
+		})
+	}
+}
+
+func sampleResults() (runSummary, []blockResult) {
+	results := []blockResult{
+		{File: "/api/users.http", Block: HTTPBlock{ID: 1, Name: "login"}, Method: "POST", URL: "http://localhost/auth", Status: "200 OK", Outcome: outcomePassed, Duration: 120 * time.Millisecond},
+		{
+			File: "/api/users.http", Block: HTTPBlock{ID: 2, CommentIdentifier: "get user #1"}, Method: "GET", URL: "http://localhost/users/1", Status: "404 Not Found",
+			Outcome: outcomeFailed, Duration: 30 * time.Millisecond,
+			Failures:   []assertionFailure{{Field: "status", Expected: "200 OK", Got: "404 Not Found"}},
+			Assertions: []assertionResult{{Name: "$.id == 1", Passed: false, Got: "missing"}},
+		},
+		{File: "/api/orders.http", Block: HTTPBlock{ID: 1}, Method: "GET", URL: "http://localhost:1/orders", Outcome: outcomeErrored, Err: errors.New("connection refused")},
+		{File: "/api/orders.http", Block: HTTPBlock{ID: 2, Note: true}, Method: "DELETE", URL: "http://localhost/orders/1", Outcome: outcomeSkipped},
+	}
+	var summary runSummary
+	for _, result := range results {
+		summary.add(result)
+	}
+	summary.Duration = 200 * time.Millisecond
+	return summary, results
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	summary, results := sampleResults()
+	var out bytes.Buffer
+	if err := writeJUnitReport(&out, summary, results); err != nil {
+		t.Fatalf("got error on function writeJUnitReport: %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, out.String())
+	}
+	if report.Tests != 4 || report.Failures != 1 || report.Errors != 1 || report.Skipped != 1 || len(report.Suites) != 2 {
+		t.Fatalf("Incorrect report: %+v", report)
+	}
+	users := report.Suites[0]
+	if users.Name != "users.http" || users.Tests != 2 || users.Time != "0.150" {
+		t.Errorf("Incorrect suite: %+v", users)
+	}
+	if users.Cases[0].Name != "login" || users.Cases[0].Time != "0.120" || users.Cases[0].Failure != nil {
+		t.Errorf("Incorrect passed case: %+v", users.Cases[0])
+	}
+	failure := users.Cases[1].Failure
+	if users.Cases[1].Name != "get user #1" || failure == nil || failure.Message != "[ status ] Expected: [ 200 OK ] Got: [ 404 Not Found ]" || !strings.Contains(failure.Text, "[X] $.id == 1 Got: [ missing ]") {
+		t.Errorf("Incorrect failed case: %+v", users.Cases[1])
+	}
+	orders := report.Suites[1]
+	if orders.Cases[0].Name != "GET http://localhost:1/orders" || orders.Cases[0].Error == nil || orders.Cases[1].Skipped == nil {
+		t.Errorf("Incorrect suite: %+v", orders)
+	}
+}
+
+func TestWriteTAPReport(t *testing.T) {
+	summary, results := sampleResults()
+	var out bytes.Buffer
+	if err := writeTAPReport(&out, summary, results); err != nil {
+		t.Fatalf("got error on function writeTAPReport: %v", err)
+	}
+
+	for _, line := range []string{
+		"TAP version 13\n1..4\n",
+		"ok 1 - login\n",
+		"not ok 2 - get user \\#1\n",
+		"    - \"[ status ] Expected: [ 200 OK ] Got: [ 404 Not Found ]\"\n",
+		"not ok 3 - GET http://localhost:1/orders\n",
+		"    - \"connection refused\"\n",
+		"ok 4 - DELETE http://localhost/orders/1 # SKIP not confirmed\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %q in:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	summary, results := sampleResults()
+	var out bytes.Buffer
+	if err := writeJSONReport(&out, summary, results); err != nil {
+		t.Fatalf("got error on function writeJSONReport: %v", err)
+	}
+
+	var report jsonReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if report.Summary != (jsonSummary{Passed: 1, Failed: 1, Errored: 1, Skipped: 1, DurationMs: 200}) {
+		t.Errorf("Incorrect summary: %+v", report.Summary)
+	}
+	if len(report.Results) != 4 || report.Results[1].Failures[0].Field != "status" || report.Results[2].Error != "connection refused" {
+		t.Errorf("Incorrect results: %+v", report.Results)
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	summary, results := sampleResults()
+	results = append([]blockResult{{
+		File: "/api/health.http", Block: HTTPBlock{ID: 1}, Method: "GET", URL: "http://localhost/health", Status: "200 OK", Outcome: outcomePassed,
+		RequestHeaders:  http.Header{"Accept": []string{"application/json"}},
+		ResponseHeaders: http.Header{"Content-Type": []string{"application/json"}},
+		ResponseBody:    []byte(`{"status":"<up>"}`),
+	}}, results...)
+	summary.add(results[0])
+
+	var out bytes.Buffer
+	if err := writeHTMLReport(&out, summary, results); err != nil {
+		t.Fatalf("got error on function writeHTMLReport: %v", err)
+	}
+	report := out.String()
+
+	for _, text := range []string{
+		"2 passed", "1 failed", "1 errored", "1 skipped",
+		`<details class="block failed" open>`,
+		"[ status ] Expected: [ 200 OK ] Got: [ 404 Not Found ]",
+		"<td>Accept</td><td>application/json</td>",
+		"{\n  &#34;status&#34;: &#34;&lt;up&gt;&#34;\n}",
+	} {
+		if !strings.Contains(report, text) {
+			t.Errorf("missing %q in the report", text)
+		}
+	}
+	// Files with failures come first
+	users, orders, health := strings.Index(report, "users.http"), strings.Index(report, "orders.http"), strings.Index(report, "health.http")
+	if users > orders || orders > health {
+		t.Errorf("Incorrect file order: users %d, orders %d, health %d", users, orders, health)
+	}
+	if !strings.Contains(report, `<details class="file clean" open>`) {
+		t.Errorf("health.http should be marked clean")
+	}
+}
+
+func TestWriteHTMLReportRedacts(t *testing.T) {
+	result := blockResult{
+		File: "/api/login.http", Block: HTTPBlock{ID: 1}, Method: "POST", URL: "http://localhost/login", Status: "200 OK", Outcome: outcomePassed,
+		RequestHeaders:  http.Header{"Authorization": []string{"Bearer abc123"}, "Accept": []string{"application/json"}},
+		RequestBody:     `{"password": "hunter2"}`,
+		ResponseHeaders: http.Header{"Set-Cookie": []string{"session=s3cr3t"}},
+		Secrets:         []string{"hunter2"},
+	}
+	var summary runSummary
+	summary.add(result)
+
+	var out bytes.Buffer
+	if err := writeHTMLReport(&out, summary, []blockResult{result}); err != nil {
+		t.Fatalf("got error on function writeHTMLReport: %v", err)
+	}
+	report := out.String()
+
+	for _, secret := range []string{"abc123", "hunter2", "s3cr3t"} {
+		if strings.Contains(report, secret) {
+			t.Errorf("%s should be redacted in the report", secret)
+		}
+	}
+	for _, text := range []string{"<td>Authorization</td><td>[redacted]</td>", "<td>Accept</td><td>application/json</td>"} {
+		if !strings.Contains(report, text) {
+			t.Errorf("missing %q in the report", text)
+		}
+	}
+	if result.RequestHeaders.Get("Authorization") != "Bearer abc123" {
+		t.Errorf("the headers of the result shouldn't change")
+	}
+}
+
+func TestReportBody(t *testing.T) {
+	if body := reportBody([]byte{0xff, 0xfe, 0x00}); body != "<3 bytes of binary data>" {
+		t.Errorf("Incorrect binary body: %q", body)
+	}
+	if body := reportBody([]byte("plain text")); body != "plain text" {
+		t.Errorf("Incorrect text body: %q", body)
+	}
+	long := reportBody([]byte(strings.Repeat("a", maxReportBody+10)))
+	if !strings.HasSuffix(long, "\n... 10 more bytes") {
+		t.Errorf("Incorrect truncated body: %q", long[len(long)-30:])
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// requestExchange is what a named block sent and received during a run, kept
+// so later blocks can reference it with {{login.response.body.$.token}}
+type requestExchange struct {
+	Request         HTTPRequest
+	Status          string
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+}
+
+// Placeholders like {{login.response.body.$.token}} or {{login.request.headers.Content-Type}}
+var reRequestVariable = regexp.MustCompile(`\{\{\s*([\w-]+)\.(request|response)\.(body|headers)\.([^}]+?)\s*\}\}`)
+
+// resolveRequestVariables returns a copy of req with the request variables replaced
+// by values from the named blocks already sent in this run. References that
+// cannot be resolved are left untouched and reported as errors.
+func resolveRequestVariables(req HTTPRequest, exchanges map[string]*requestExchange) (HTTPRequest, []error) {
+	var errs []error
+	resolved := mapRequestText(req, func(text string) string {
+		return reRequestVariable.ReplaceAllStringFunc(text, func(match string) string {
+			parts := reRequestVariable.FindStringSubmatch(match)
+			value, err := lookupRequestVariable(exchanges, parts[1], parts[2], parts[3], parts[4])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", match, err))
+				return match
+			}
+			return value
+		})
+	})
+	return resolved, errs
+}
+
+// mapRequestText returns a copy of req with resolve applied to the URL, the header values and the body
+func mapRequestText(req HTTPRequest, resolve func(string) string) HTTPRequest {
+	resolved := req
+	resolved.Url = resolve(req.Url)
+	resolved.Body = resolve(req.Body)
+	resolved.Headers = make(map[string]string, len(req.Headers))
+	for key, value := range req.Headers {
+		resolved.Headers[key] = resolve(value)
+	}
+
+	// The body may have changed size
+	if _, exists := resolved.Headers["Content-Length"]; exists && resolved.Body != req.Body {
+		resolved.Headers["Content-Length"] = strconv.Itoa(len(resolved.Body))
+	}
+
+	return resolved
+}
+
+// lookupRequestVariable finds the value of {{name.source.part.selector}}
+func lookupRequestVariable(exchanges map[string]*requestExchange, name, source, part, selector string) (string, error) {
+	exchange, ok := exchanges[name]
+	if !ok {
+		return "", fmt.Errorf("no response from a block named %q yet", name)
+	}
+
+	var body []byte
+	headers := make(http.Header)
+	if source == "request" {
+		body = []byte(exchange.Request.Body)
+		for key, value := range exchange.Request.Headers {
+			headers.Set(key, value)
+		}
+	} else {
+		body = exchange.ResponseBody
+		headers = exchange.ResponseHeaders
+	}
+
+	if part == "headers" {
+		values := headers.Values(selector)
+		if len(values) == 0 {
+			return "", fmt.Errorf("header %q not found", selector)
+		}
+		return strings.Join(values, ", "), nil
+	}
+
+	if selector == "*" {
+		return string(body), nil
+	}
+	if !strings.HasPrefix(selector, "$") {
+		return "", fmt.Errorf("unsupported body selector %q, use * or a JSONPath", selector)
+	}
+	data, err := decodeJSON(body)
+	if err != nil {
+		return "", fmt.Errorf("%s body is not JSON: %w", source, err)
+	}
+	value, err := evaluateJSONPath(data, selector)
+	if err != nil {
+		return "", err
+	}
+	return formatJSONValue(value), nil
+}
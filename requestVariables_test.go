@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResolveRequestVariables(t *testing.T) {
+	exchanges := map[string]*requestExchange{
+		"login": {
+			Request: HTTPRequest{
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    `{"user":"admin"}`,
+			},
+			Status:          "200 OK",
+			ResponseHeaders: http.Header{"Location": []string{"http://localhost:8080/users/1"}},
+			ResponseBody:    []byte(`{"token":"abc","user":{"id":7,"roles":["admin","dev"]}}`),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		errors   int
+	}{
+		{"jsonpath string", "{{login.response.body.$.token}}", "abc", 0},
+		{"jsonpath number", "{{login.response.body.$.user.id}}", "7", 0},
+		{"jsonpath index", "{{login.response.body.$.user.roles[1]}}", "dev", 0},
+		{"jsonpath object", "{{login.response.body.$.user.roles}}", `["admin","dev"]`, 0},
+		{"whole body", "{{login.request.body.*}}", `{"user":"admin"}`, 0},
+		{"response header", "{{ login.response.headers.location }}", "http://localhost:8080/users/1", 0},
+		{"request header", "{{login.request.headers.Content-Type}}", "application/json", 0},
+		{"unknown block", "{{other.response.body.$.token}}", "{{other.response.body.$.token}}", 1},
+		{"missing key", "{{login.response.body.$.nope}}", "{{login.response.body.$.nope}}", 1},
+		{"not a request variable", "{{baseUrl}}", "{{baseUrl}}", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := HTTPRequest{Url: tc.text, Headers: map[string]string{}}
+			resolved, errs := resolveRequestVariables(req, exchanges)
+			if resolved.Url != tc.expected {
+				t.Errorf("expected: %s, Got: %s", tc.expected, resolved.Url)
+			}
+			if len(errs) != tc.errors {
+				t.Errorf("expected %d errors, Got: %v", tc.errors, errs)
+			}
+		})
+	}
+}
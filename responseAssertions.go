@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// assertionFailure is one difference between what a block expected and what it got
+type assertionFailure struct {
+	Field    string // status, header Content-Type, body $.user.id...
+	Expected string
+	Got      string
+	Message  string // printed instead of Expected and Got when set
+}
+
+// compareResponse checks the response against the expected response block:
+//   - the status code
+//   - every expected header, a header without value only has to be present
+//   - the body, JSON bodies ignore key order and whitespace and the expected
+//     JSON only has to be a subset of the actual one, other bodies are compared as text
+//
+// Status lines like `HTTP/1.1 2xx` and matchers like {{$number}} accept values
+// changing every run, see responseMatcher.
+func compareResponse(expected *http.Response, expectedBody string, resp *http.Response, body []byte) []assertionFailure {
+	var failures []assertionFailure
+
+	if class, ok := statusClass(expected.Status); ok {
+		// HTTP/1.1 2xx
+		if resp.StatusCode/100 != class {
+			failures = append(failures, assertionFailure{Field: "status", Expected: expected.Status, Got: resp.Status})
+		}
+	} else if expected.StatusCode != 0 && expected.StatusCode != resp.StatusCode {
+		failures = append(failures, assertionFailure{Field: "status", Expected: expected.Status, Got: resp.Status})
+	}
+
+	// Sorted so the failures are always printed in the same order
+	names := make([]string, 0, len(expected.Header))
+	for name := range expected.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := "header " + name
+		expectedValue := expected.Header.Get(name)
+		values, present := resp.Header[name]
+		if !present {
+			failures = append(failures, assertionFailure{Field: field, Expected: presenceOr(expectedValue), Got: "missing"})
+			continue
+		}
+		if expectedValue == "" {
+			continue
+		}
+
+		got := strings.Join(values, ", ")
+		parts, err := splitMatchers(expectedValue)
+		if err != nil {
+			failures = append(failures, assertionFailure{Field: field, Expected: expectedValue, Got: err.Error()})
+		} else if hasMatchers(parts) {
+			if !matchText(parts, got) {
+				failures = append(failures, assertionFailure{Field: field, Expected: expectedValue, Got: got})
+			}
+		} else if got != expectedValue {
+			failures = append(failures, assertionFailure{Field: field, Expected: expectedValue, Got: got})
+		}
+	}
+
+	failures = append(failures, compareBody(expectedBody, body)...)
+	return failures
+}
+
+func presenceOr(value string) string {
+	if value == "" {
+		return "present"
+	}
+	return value
+}
+
+func compareBody(expectedBody string, body []byte) []assertionFailure {
+	expectedBody = strings.TrimSpace(expectedBody)
+	if expectedBody == "" {
+		return nil
+	}
+	actualBody := strings.TrimSpace(string(body))
+
+	parts, err := splitMatchers(expectedBody)
+	if err != nil {
+		return []assertionFailure{{Field: "body", Expected: expectedBody, Got: err.Error()}}
+	}
+	jsonText, matchers := expectedJSON(parts)
+
+	expected, err := decodeJSON([]byte(jsonText))
+	if err != nil {
+		if !matchText(parts, actualBody) {
+			return []assertionFailure{{Field: "body", Expected: expectedBody, Got: actualBody}}
+		}
+		return nil
+	}
+
+	actualJSON, err := decodeJSON(body)
+	if err != nil {
+		// A body made only of a matcher is valid JSON too, it can match a text body
+		if matchText(parts, actualBody) {
+			return nil
+		}
+		if _, isString := expected.(string); isString {
+			return []assertionFailure{{Field: "body", Expected: expectedBody, Got: truncate(actualBody, 80)}}
+		}
+		return []assertionFailure{{Field: "body", Expected: "JSON", Got: truncate(actualBody, 80)}}
+	}
+
+	var failures []assertionFailure
+	compareJSON(expected, actualJSON, "$", matchers, &failures)
+	return failures
+}
+
+// compareJSON checks that expected is a subset of actual, objects may have
+// more keys than expected while arrays must have the same length
+func compareJSON(expected, actual any, path string, matchers []*responseMatcher, failures *[]assertionFailure) {
+	field := "body " + path
+
+	// Strings holding matchers, see expectedJSON
+	if s, ok := expected.(string); ok {
+		if parts, ok := stringMatchers(s, matchers); ok {
+			var matched bool
+			if len(parts) == 1 && parts[0].Matcher != nil {
+				matched = parts[0].Matcher.matchValue(actual)
+			} else {
+				a, isString := actual.(string)
+				matched = isString && matchText(parts, a)
+			}
+			if !matched {
+				*failures = append(*failures, assertionFailure{Field: field, Expected: describeMatchers(parts), Got: formatJSONValue(actual)})
+			}
+			return
+		}
+	}
+
+	switch e := expected.(type) {
+	case map[string]any:
+		a, ok := actual.(map[string]any)
+		if !ok {
+			*failures = append(*failures, assertionFailure{Field: field, Expected: "object", Got: jsonTypeName(actual)})
+			return
+		}
+		keys := make([]string, 0, len(e))
+		for key := range e {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, ok := a[key]
+			if !ok {
+				*failures = append(*failures, assertionFailure{Field: field + "." + key, Expected: describeExpectedJSON(e[key], matchers), Got: "missing"})
+				continue
+			}
+			compareJSON(e[key], value, path+"."+key, matchers, failures)
+		}
+	case []any:
+		a, ok := actual.([]any)
+		if !ok {
+			*failures = append(*failures, assertionFailure{Field: field, Expected: "array", Got: jsonTypeName(actual)})
+			return
+		}
+		if len(e) != len(a) {
+			*failures = append(*failures, assertionFailure{Field: field, Expected: fmt.Sprintf("%d items", len(e)), Got: fmt.Sprintf("%d items", len(a))})
+			return
+		}
+		for i := range e {
+			compareJSON(e[i], a[i], fmt.Sprintf("%s[%d]", path, i), matchers, failures)
+		}
+	case json.Number:
+		a, ok := actual.(json.Number)
+		if !ok || !equalNumbers(e, a) {
+			*failures = append(*failures, assertionFailure{Field: field, Expected: e.String(), Got: formatJSONValue(actual)})
+		}
+	default:
+		if expected != actual {
+			*failures = append(*failures, assertionFailure{Field: field, Expected: formatJSONValue(expected), Got: formatJSONValue(actual)})
+		}
+	}
+}
+
+// describeExpectedJSON prints an expected value with its matchers as they were written
+func describeExpectedJSON(value any, matchers []*responseMatcher) string {
+	if s, ok := value.(string); ok {
+		if parts, ok := stringMatchers(s, matchers); ok {
+			return describeMatchers(parts)
+		}
+	}
+	return formatJSONValue(value)
+}
+
+// statusClass reads status lines like `2xx` that accept any status of a class
+func statusClass(status string) (int, bool) {
+	code, _, _ := strings.Cut(status, " ")
+	if len(code) != 3 || !strings.EqualFold(code[1:], "xx") || code[0] < '1' || code[0] > '5' {
+		return 0, false
+	}
+	return int(code[0] - '0'), true
+}
+
+// equalNumbers compares numbers by value, so 1 and 1.0 are the same
+func equalNumbers(a, b json.Number) bool {
+	if a == b {
+		return true
+	}
+	x, errX := strconv.ParseFloat(a.String(), 64)
+	y, errY := strconv.ParseFloat(b.String(), 64)
+	return errX == nil && errY == nil && x == y
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func truncate(text string, length int) string {
+	if len(text) <= length {
+		return text
+	}
+	return text[:length] + "..."
+}
+
+// printFailures prints the failed block followed by one line per failure
+func printFailures(block HTTPBlock, method string, url string, failures []assertionFailure) {
+	if block.CommentIdentifier != "" {
+		fmt.Printf("%s%s%s\n", C_Purple, block.CommentIdentifier, C_Reset)
+	}
+	fmt.Printf("%s[X] %s %s%s\n", C_Red, method, url, C_Reset)
+	for _, failure := range failures {
+		fmt.Printf("%s    %s%s\n", C_Red, failureText(failure), C_Reset)
+	}
+}
+
+// failureText is how a failure is printed, also used by the reporters
+func failureText(failure assertionFailure) string {
+	if failure.Message != "" {
+		return fmt.Sprintf("[ %s ] %s", failure.Field, failure.Message)
+	}
+	return fmt.Sprintf("[ %s ] Expected: [ %s ] Got: [ %s ]", failure.Field, failure.Expected, failure.Got)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCompareResponse(t *testing.T) {
+	resp := &http.Response{
+		Status:     "201 Created",
+		StatusCode: 201,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+			"X-Request-Id": []string{"42"},
+		},
+	}
+	body := []byte(`{"message": "created", "user": {"id": 123, "roles": ["admin"], "email": "a@b.c"}, "extra": true}`)
+
+	tests := []struct {
+		name     string
+		expected string
+		failures []string
+	}{
+		{"match", "HTTP/1.1 201 Created\nContent-Type: application/json\n\n{\"user\": {\"roles\": [\"admin\"], \"id\": 123.0}}", nil},
+		{"presence only header", "HTTP/1.1 201 Created\nX-Request-Id:\n", nil},
+		{"status", "HTTP/1.1 200 OK\n", []string{"status"}},
+		{"header value", "HTTP/1.1 201 Created\nContent-Type: text/plain\n", []string{"header Content-Type"}},
+		{"missing header", "HTTP/1.1 201 Created\nLocation:\n", []string{"header Location"}},
+		{
+			"json body",
+			"HTTP/1.1 201 Created\n\n{\"message\": \"ok\", \"user\": {\"id\": 124, \"name\": \"x\", \"roles\": []}}",
+			[]string{"body $.message", "body $.user.id", "body $.user.name", "body $.user.roles"},
+		},
+		{"text body", "HTTP/1.1 201 Created\n\nplain text", []string{"body"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := parseHTTPResponse(tc.expected)
+			if err != nil {
+				t.Fatalf("got error on function parseHTTPResponse: %v", err)
+			}
+			expected := &http.Response{Status: parsed.StatusText, StatusCode: parsed.StatusCode, Header: make(http.Header)}
+			for key, value := range parsed.ResponseHeaders {
+				expected.Header.Set(key, value)
+			}
+
+			failures := compareResponse(expected, parsed.ResponseBody, resp, body)
+			if len(failures) != len(tc.failures) {
+				t.Fatalf("expected failures %v, Got: %+v", tc.failures, failures)
+			}
+			for i, failure := range failures {
+				if failure.Field != tc.failures[i] {
+					t.Errorf("Incorrect failure [%d]. expected: %s, Got: %s", i, tc.failures[i], failure.Field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+)
+
+// decodeBody undoes the Content-Encoding of a body, encodings are listed in the order
+// they were applied so they are undone from the last one
+func decodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var reader io.Reader
+		var err error
+		switch encoding := strings.ToLower(strings.TrimSpace(encodings[i])); encoding {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(bytes.NewReader(body))
+		case "deflate":
+			// deflate is meant to be zlib, but some servers send raw deflate
+			reader, err = zlib.NewReader(bytes.NewReader(body))
+			if err != nil {
+				reader, err = flate.NewReader(bytes.NewReader(body)), nil
+			}
+		case "br":
+			reader = brotli.NewReader(bytes.NewReader(body))
+		default:
+			return body, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+		}
+		if err != nil {
+			return body, fmt.Errorf("decoding %s body: %w", encodings[i], err)
+		}
+		decoded, err := io.ReadAll(reader)
+		if err != nil {
+			return body, fmt.Errorf("decoding %s body: %w", encodings[i], err)
+		}
+		body = decoded
+	}
+	return body, nil
+}
+
+// Kinds of bodies, they are indented and colored differently
+const (
+	bodyText = iota
+	bodyJSON
+	bodyXML
+	bodyHTML
+	bodyBinary
+)
+
+// bodyKind guesses the kind of a body from its Content-Type, then from its content
+func bodyKind(contentType string, body []byte) int {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "video/"),
+		mediaType == "application/octet-stream", mediaType == "application/pdf", mediaType == "application/zip",
+		!utf8.Valid(body):
+		return bodyBinary
+	case strings.HasSuffix(mediaType, "json"):
+		return bodyJSON
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return bodyHTML
+	case strings.HasSuffix(mediaType, "xml"):
+		return bodyXML
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case json.Valid(trimmed) && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		return bodyJSON
+	case bytes.HasPrefix(bytes.ToLower(trimmed), []byte("<!doctype html")), bytes.HasPrefix(bytes.ToLower(trimmed), []byte("<html")):
+		return bodyHTML
+	case bytes.HasPrefix(trimmed, []byte("<?xml")):
+		return bodyXML
+	}
+	return bodyText
+}
+
+// formatBody returns the body as printed by --show-body: indented, cut after limit bytes
+// when limit is positive and colored
+func formatBody(contentType string, body []byte, limit int) string {
+	if len(body) == 0 {
+		return fmt.Sprintf("%s<empty body>%s", C_Gray, C_Reset)
+	}
+
+	kind := bodyKind(contentType, body)
+	text := strings.TrimSpace(string(body))
+	switch kind {
+	case bodyBinary:
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType == "" {
+			mediaType = "unknown type"
+		}
+		return fmt.Sprintf("%s<%d bytes of %s>%s", C_Gray, len(body), mediaType, C_Reset)
+	case bodyJSON:
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, []byte(text), "", "  "); err == nil {
+			text = indented.String()
+		}
+	case bodyXML, bodyHTML:
+		text = indentMarkup(text, kind == bodyHTML)
+	}
+
+	cut := 0
+	if limit > 0 && len(text) > limit {
+		end := limit
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		cut = len(text) - end
+		text = text[:end]
+	}
+
+	switch kind {
+	case bodyJSON:
+		text = colorJSON(text)
+	case bodyXML, bodyHTML:
+		text = colorMarkup(text)
+	}
+	if cut > 0 {
+		text += fmt.Sprintf("\n%s... %d more bytes, see --show-body-limit%s", C_Gray, cut, C_Reset)
+	}
+	return text
+}
+
+// colorJSON colors indented JSON: keys cyan, strings green, numbers yellow, true, false and null purple
+func colorJSON(text string) string {
+	var colored strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(text))
+			color := C_Green
+			if rest := strings.TrimLeft(text[end:], " "); strings.HasPrefix(rest, ":") {
+				color = C_Cyan
+			}
+			colored.WriteString(color + text[i:end] + C_Reset)
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+			colored.WriteString(C_Yellow + text[i:end] + C_Reset)
+			i = end
+		case strings.HasPrefix(text[i:], "true"), strings.HasPrefix(text[i:], "null"):
+			colored.WriteString(C_Purple + text[i:i+4] + C_Reset)
+			i += 4
+		case strings.HasPrefix(text[i:], "false"):
+			colored.WriteString(C_Purple + text[i:i+5] + C_Reset)
+			i += 5
+		default:
+			colored.WriteByte(c)
+			i++
+		}
+	}
+	return colored.String()
+}
+
+// Tags, comments, CDATA and declarations of XML and HTML
+var markupToken = regexp.MustCompile(`(?s)<!--.*?-->|<!\[CDATA\[.*?\]\]>|<[!?][^>]*>|</?[A-Za-z][^>]*>`)
+
+// HTML elements without closing tag
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// HTML elements whose content is kept as it is
+var htmlRawElements = map[string]bool{"script": true, "style": true, "pre": true, "textarea": true}
+
+func markupTagName(tag string) string {
+	name := strings.TrimLeft(tag, "</")
+	if end := strings.IndexAny(name, " \t\r\n/>"); end >= 0 {
+		name = name[:end]
+	}
+	return strings.ToLower(name)
+}
+
+// indentMarkup puts each tag on its own line indented by depth, an element with only text
+// stays on one line
+func indentMarkup(text string, html bool) string {
+	var lines []string
+	depth := 0
+	write := func(line string) {
+		lines = append(lines, strings.Repeat("  ", depth)+line)
+	}
+
+	tokens := markupToken.FindAllStringIndex(text, -1)
+	position := 0
+	for t := 0; t < len(tokens); t++ {
+		start, end := tokens[t][0], tokens[t][1]
+		if content := strings.TrimSpace(text[position:start]); content != "" {
+			write(content)
+		}
+		position = end
+		tag := text[start:end]
+		name := markupTagName(tag)
+
+		switch {
+		case strings.HasPrefix(tag, "</"):
+			depth = max(depth-1, 0)
+			write(tag)
+		case strings.HasPrefix(tag, "<!") || strings.HasPrefix(tag, "<?") || strings.HasSuffix(tag, "/>") || (html && htmlVoidElements[name]):
+			write(tag)
+		case html && htmlRawElements[name]:
+			// Everything up to the closing tag as it is
+			closing := strings.Index(strings.ToLower(text[end:]), "</"+name)
+			if closing < 0 {
+				write(tag + text[end:])
+				return strings.Join(lines, "\n")
+			}
+			closeEnd := end + closing + strings.IndexByte(text[end+closing:], '>') + 1
+			write(tag + text[end:closeEnd])
+			position = closeEnd
+			for t+1 < len(tokens) && tokens[t+1][0] < closeEnd {
+				t++
+			}
+		case t+1 < len(tokens) && strings.HasPrefix(text[tokens[t+1][0]:tokens[t+1][1]], "</") &&
+			markupTagName(text[tokens[t+1][0]:tokens[t+1][1]]) == name:
+			// <name>text</name> on one line
+			next := tokens[t+1]
+			write(tag + strings.TrimSpace(text[end:next[0]]) + text[next[0]:next[1]])
+			position = next[1]
+			t++
+		default:
+			write(tag)
+			depth++
+		}
+	}
+	if content := strings.TrimSpace(text[position:]); content != "" {
+		write(content)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// colorMarkup colors the tags blue and the comments gray
+func colorMarkup(text string) string {
+	return markupToken.ReplaceAllStringFunc(text, func(tag string) string {
+		if strings.HasPrefix(tag, "<!--") {
+			return C_Gray + tag + C_Reset
+		}
+		return C_Blue + tag + C_Reset
+	})
+}
+
+// printResponseHeaders prints the headers of a response sorted by name
+func printResponseHeaders(headers http.Header, indent string) {
+	for _, header := range htmlHeaders(headers) {
+		fmt.Printf("%s%s%s:%s %s\n", indent, C_Cyan, header.Name, C_Reset, header.Value)
+	}
+}
+
+// printResponseBody prints a body formatted by formatBody
+func printResponseBody(headers http.Header, body []byte, limit int, indent string) {
+	for _, line := range strings.Split(formatBody(headers.Get("Content-Type"), body, limit), "\n") {
+		fmt.Printf("%s%s\n", indent, line)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "zlib":
+		writer = zlib.NewWriter(&buf)
+	case "flate":
+		writer, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	}
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	body := []byte(`{"id": 1}`)
+	tests := []struct {
+		name     string
+		encoded  []byte
+		encoding string
+	}{
+		{"identity", body, "identity"},
+		{"gzip", compress(t, "gzip", body), "gzip"},
+		{"deflate", compress(t, "zlib", body), "deflate"},
+		{"raw deflate", compress(t, "flate", body), "deflate"},
+		{"brotli", compress(t, "br", body), "br"},
+		{"gzip then brotli", compress(t, "br", compress(t, "gzip", body)), "gzip, br"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := decodeBody(tc.encoded, tc.encoding)
+			if err != nil {
+				t.Fatalf("got error on function decodeBody: %v", err)
+			}
+			if !bytes.Equal(decoded, body) {
+				t.Errorf("Incorrect body. expected: %s, Got: %q", body, decoded)
+			}
+		})
+	}
+
+	if _, err := decodeBody(body, "compress"); err == nil {
+		t.Errorf("expected an error for an unsupported encoding")
+	}
+	if decoded, err := decodeBody(body, "gzip"); err == nil || !bytes.Equal(decoded, body) {
+		t.Errorf("a body that isn't gzip should be kept with an error, Got: %q %v", decoded, err)
+	}
+}
+
+var ansiColors = regexp.MustCompile(`\033\[[0-9;]*m`)
+
+func TestFormatBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		limit       int
+		expected    string
+	}{
+		{"json", "application/json", `{"id":1,"tags":["a"],"ok":true,"next":null}`, 0, "{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ],\n  \"ok\": true,\n  \"next\": null\n}"},
+		{"json without content type", "", `[{"id": -1.5e3}]`, 0, "[\n  {\n    \"id\": -1.5e3\n  }\n]"},
+		{"problem json", "application/problem+json; charset=utf-8", `{"title":"Not Found"}`, 0, "{\n  \"title\": \"Not Found\"\n}"},
+		{"xml", "application/xml", `<?xml version="1.0"?><users><user id="1"><name>Ada</name><admin/></user><!-- end --></users>`, 0,
+			"<?xml version=\"1.0\"?>\n<users>\n  <user id=\"1\">\n    <name>Ada</name>\n    <admin/>\n  </user>\n  <!-- end -->\n</users>"},
+		{"html", "text/html; charset=utf-8", "<!DOCTYPE html><html><head><meta charset=\"utf-8\"><script>if (a < b) { go() }</script></head><body><p>Hi <b>there</b></p><br></body></html>", 0,
+			"<!DOCTYPE html>\n<html>\n  <head>\n    <meta charset=\"utf-8\">\n    <script>if (a < b) { go() }</script>\n  </head>\n  <body>\n    <p>\n      Hi\n      <b>there</b>\n    </p>\n    <br>\n  </body>\n</html>"},
+		{"text", "text/plain", "  hello  \n", 0, "hello"},
+		{"truncated", "text/plain", "héllo world", 2, "h\n... 11 more bytes, see --show-body-limit"},
+		{"binary", "image/png", "\x89PNG\r\n", 0, "<6 bytes of image/png>"},
+		{"binary without content type", "", "\xff\xfe\x00", 0, "<3 bytes of unknown type>"},
+		{"empty", "application/json", "", 0, "<empty body>"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ansiColors.ReplaceAllString(formatBody(tc.contentType, []byte(tc.body), tc.limit), "")
+			if got != tc.expected {
+				t.Errorf("Incorrect body. expected:\n%s\nGot:\n%s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestColorJSON(t *testing.T) {
+	got := colorJSON(`{"a\"b": "x", "n": 2, "f": false}`)
+	expected := "{" + C_Cyan + `"a\"b"` + C_Reset + ": " + C_Green + `"x"` + C_Reset + ", " +
+		C_Cyan + `"n"` + C_Reset + ": " + C_Yellow + "2" + C_Reset + ", " +
+		C_Cyan + `"f"` + C_Reset + ": " + C_Purple + "false" + C_Reset + "}"
+	if got != expected {
+		t.Errorf("Incorrect colors. expected: %q, Got: %q", expected, got)
+	}
+}
+
+func TestSendBlockDecodesBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(compress(t, "br", []byte(`{"id": 7}`)))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "api.http")
+	content := fmt.Sprintf("// @assert $.id == 7\nGET %s/users/7 HTTP/1.1\nAccept-Encoding: br", server.URL)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{HTTPFilePath: path, HTTPRequestTimeout: 2000}
+	httpFileContent, err := processHTTPFiles(config)
+	if err != nil {
+		t.Fatalf("got error on function processHTTPFiles: %v", err)
+	}
+
+	result := sendBlock(context.Background(), httpFileContent[0], httpFileContent[0].Blocks[0], config, newRunState())
+	if result.Outcome != outcomePassed || string(result.ResponseBody) != `{"id": 7}` || len(result.Warnings) != 0 {
+		t.Errorf("Incorrect result: %s %q %v %v", result.Outcome, result.ResponseBody, result.Warnings, result.Assertions)
+	}
+	if !strings.Contains(formatBody(result.ResponseHeaders.Get("Content-Type"), result.ResponseBody, 0), `"id"`) {
+		t.Errorf("the decoded body should be formatted as JSON")
+	}
+}
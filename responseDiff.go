@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Headers left out of the --diff, they change on every run
+var responseDiffIgnoredHeaders = []string{"Date", "Age", "Expires"}
+
+// previousResponse is the last response of a block in the watch session
+type previousResponse struct {
+	Run  int
+	Text string
+}
+
+var (
+	// Last response of each block, for --diff
+	previousResponsesMu sync.Mutex
+	previousResponses   = make(map[string]previousResponse)
+)
+
+// responseDiffKey names a block like its snapshot: by `// @name` when it has one
+func responseDiffKey(result blockResult) string {
+	key := result.Block.Name
+	if key == "" {
+		key = strconv.Itoa(result.Block.ID)
+	}
+	return result.File + "#" + key
+}
+
+// responseDiffText is the status, headers and body of a response, JSON bodies pretty printed
+func responseDiffText(result blockResult) string {
+	var text strings.Builder
+	text.WriteString(result.Status + "\n")
+
+	var names []string
+	for name := range result.ResponseHeaders {
+		ignored := false
+		for _, ignoredName := range responseDiffIgnoredHeaders {
+			ignored = ignored || strings.EqualFold(name, ignoredName)
+		}
+		if !ignored {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&text, "%s: %s\n", name, strings.Join(result.ResponseHeaders.Values(name), ", "))
+	}
+	text.WriteString("\n")
+
+	body := string(result.ResponseBody)
+	if data, err := decodeJSON(result.ResponseBody); err == nil {
+		if pretty, err := prettyJSON(data); err == nil {
+			body = pretty
+		}
+	}
+	text.WriteString(body)
+	return strings.TrimRight(text.String(), "\n")
+}
+
+// diffWithPreviousRun keeps the response of a block and returns how it changed since the
+// last time the block got one, found is false the first time
+func diffWithPreviousRun(run int, result blockResult) (diff []string, found bool) {
+	key, text := responseDiffKey(result), responseDiffText(result)
+
+	previousResponsesMu.Lock()
+	previous, found := previousResponses[key]
+	previousResponses[key] = previousResponse{Run: run, Text: text}
+	previousResponsesMu.Unlock()
+
+	if !found {
+		return nil, false
+	}
+	return unifiedDiff(fmt.Sprintf("run %d", previous.Run), fmt.Sprintf("run %d", run), previous.Text, text), true
+}
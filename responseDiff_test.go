@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDiffWithPreviousRun(t *testing.T) {
+	defer func() { previousResponses = make(map[string]previousResponse) }()
+
+	response := func(status, date, body string) blockResult {
+		return blockResult{
+			File:            "/api/users.http",
+			Block:           HTTPBlock{ID: 2, Name: "user"},
+			Status:          status,
+			ResponseHeaders: http.Header{"Content-Type": []string{"application/json"}, "Date": []string{date}},
+			ResponseBody:    []byte(body),
+		}
+	}
+
+	if diff, found := diffWithPreviousRun(1, response("200 OK", "Mon", `{"id": 1, "name": "a"}`)); found || diff != nil {
+		t.Errorf("the first run has nothing to compare with, Got: %v %v", found, diff)
+	}
+	// Only the Date and the order of the keys changed
+	if diff, found := diffWithPreviousRun(2, response("200 OK", "Tue", `{"name": "a", "id": 1}`)); !found || len(diff) != 0 {
+		t.Errorf("expected an unchanged response, Got: %v %v", found, diff)
+	}
+
+	diff, found := diffWithPreviousRun(3, response("201 Created", "Wed", `{"id": 1, "name": "b"}`))
+	expected := []string{
+		"--- run 2",
+		"+++ run 3",
+		"@@ -1,7 +1,7 @@",
+		"-200 OK",
+		"+201 Created",
+		" Content-Type: application/json",
+		" ",
+		" {",
+		`   "id": 1,`,
+		`-  "name": "a"`,
+		`+  "name": "b"`,
+		" }",
+	}
+	if !found || strings.Join(diff, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Incorrect diff. expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(diff, "\n"))
+	}
+
+	// Blocks are told apart by file and name
+	other := response("200 OK", "Wed", `{}`)
+	other.Block.Name = "other"
+	if _, found := diffWithPreviousRun(3, other); found {
+		t.Errorf("another block shouldn't be compared with user")
+	}
+}
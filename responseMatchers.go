@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// responseMatcher is a token of an expected response block that matches values
+// changing every run instead of a literal value:
+//
+//	{{$any}}            anything
+//	{{$number}}         any number
+//	{{$isoDate}}        an ISO 8601 date like 2024-03-05 or 2024-03-05T14:07:09Z
+//	{{$regex pattern}}  a value matching the regular expression
+type responseMatcher struct {
+	Name  string
+	Arg   string
+	regex *regexp.Regexp
+}
+
+// matcherPart is a piece of an expected text, either literal text or a matcher
+type matcherPart struct {
+	Literal string
+	Matcher *responseMatcher
+}
+
+const isoDatePattern = `\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?)?`
+
+var reISODate = regexp.MustCompile(`^` + isoDatePattern + `$`)
+
+// Separates the index of a matcher from JSON text, see expectedJSON
+const matcherSentinel = "\x00"
+
+// splitMatchers splits an expected text into literal parts and matchers.
+// Braces are counted so regular expressions like {{$regex ^\d{3}$}} are read whole.
+func splitMatchers(text string) ([]matcherPart, error) {
+	var parts []matcherPart
+	for {
+		start := strings.Index(text, "{{$")
+		if start == -1 {
+			break
+		}
+
+		end := -1
+		depth := 0
+		for i := start + 3; i < len(text); i++ {
+			switch {
+			case depth == 0 && strings.HasPrefix(text[i:], "}}"):
+				end = i
+			case text[i] == '{':
+				depth++
+			case text[i] == '}':
+				depth--
+			}
+			if end != -1 {
+				break
+			}
+		}
+		if end == -1 {
+			break
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(text[start+3:end]), " ")
+		matcher := &responseMatcher{Name: name, Arg: strings.TrimSpace(arg)}
+		switch matcher.Name {
+		case "any", "number", "isoDate":
+		case "regex":
+			regex, err := regexp.Compile(matcher.Arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid {{$regex}}: %w", err)
+			}
+			matcher.regex = regex
+		default:
+			// Not a matcher, keep it as text
+			parts = append(parts, matcherPart{Literal: text[:end+2]})
+			text = text[end+2:]
+			continue
+		}
+
+		if start > 0 {
+			parts = append(parts, matcherPart{Literal: text[:start]})
+		}
+		parts = append(parts, matcherPart{Matcher: matcher})
+		text = text[end+2:]
+	}
+	if text != "" {
+		parts = append(parts, matcherPart{Literal: text})
+	}
+	return parts, nil
+}
+
+func hasMatchers(parts []matcherPart) bool {
+	for _, part := range parts {
+		if part.Matcher != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *responseMatcher) String() string {
+	if m.Arg != "" {
+		return "{{$" + m.Name + " " + m.Arg + "}}"
+	}
+	return "{{$" + m.Name + "}}"
+}
+
+// matchValue checks a whole JSON value
+func (m *responseMatcher) matchValue(value any) bool {
+	switch m.Name {
+	case "any":
+		return true
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "isoDate":
+		s, ok := value.(string)
+		return ok && reISODate.MatchString(s)
+	case "regex":
+		return value != nil && m.regex.MatchString(formatJSONValue(value))
+	}
+	return false
+}
+
+// pattern is the regular expression used when the matcher is part of a bigger text
+func (m *responseMatcher) pattern() string {
+	switch m.Name {
+	case "number":
+		return `-?\d+(\.\d+)?([eE][+-]?\d+)?`
+	case "isoDate":
+		return isoDatePattern
+	case "regex":
+		return "(?:" + strings.TrimSuffix(strings.TrimPrefix(m.Arg, "^"), "$") + ")"
+	}
+	return `(?s:.*)`
+}
+
+// matchText checks a text against literal parts and matchers. A regex that is the whole
+// expected text is used as written, like for JSON values: not anchored unless it says so.
+func matchText(parts []matcherPart, text string) bool {
+	if len(parts) == 1 && parts[0].Matcher != nil && parts[0].Matcher.Name == "regex" {
+		return parts[0].Matcher.regex.MatchString(text)
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for _, part := range parts {
+		if part.Matcher != nil {
+			pattern.WriteString(part.Matcher.pattern())
+		} else {
+			pattern.WriteString(regexp.QuoteMeta(part.Literal))
+		}
+	}
+	pattern.WriteString("$")
+
+	regex, err := regexp.Compile(pattern.String())
+	return err == nil && regex.MatchString(text)
+}
+
+// expectedJSON turns an expected JSON body with matchers into valid JSON: every
+// matcher becomes a string holding its index between sentinels, quoted or not
+// depending on whether the matcher is written inside a JSON string
+func expectedJSON(parts []matcherPart) (string, []*responseMatcher) {
+	var result strings.Builder
+	var matchers []*responseMatcher
+	inString := false
+
+	for _, part := range parts {
+		if part.Matcher == nil {
+			for i := 0; i < len(part.Literal); i++ {
+				c := part.Literal[i]
+				if c == '\\' && inString && i+1 < len(part.Literal) {
+					result.WriteByte(c)
+					i++
+					result.WriteByte(part.Literal[i])
+					continue
+				}
+				if c == '"' {
+					inString = !inString
+				}
+				result.WriteByte(c)
+			}
+			continue
+		}
+
+		placeholder := `\u0000` + strconv.Itoa(len(matchers)) + `\u0000`
+		matchers = append(matchers, part.Matcher)
+		if inString {
+			result.WriteString(placeholder)
+		} else {
+			result.WriteString(`"` + placeholder + `"`)
+		}
+	}
+	return result.String(), matchers
+}
+
+// stringMatchers returns the parts of an expected JSON string that holds matchers
+func stringMatchers(value string, matchers []*responseMatcher) ([]matcherPart, bool) {
+	if !strings.Contains(value, matcherSentinel) {
+		return nil, false
+	}
+	var parts []matcherPart
+	for i, piece := range strings.Split(value, matcherSentinel) {
+		if i%2 == 0 {
+			if piece != "" {
+				parts = append(parts, matcherPart{Literal: piece})
+			}
+			continue
+		}
+		index, err := strconv.Atoi(piece)
+		if err != nil || index >= len(matchers) {
+			return nil, false
+		}
+		parts = append(parts, matcherPart{Matcher: matchers[index]})
+	}
+	return parts, true
+}
+
+// describeMatchers prints the parts back as they were written in the expected block
+func describeMatchers(parts []matcherPart) string {
+	var result strings.Builder
+	for _, part := range parts {
+		if part.Matcher != nil {
+			result.WriteString(part.Matcher.String())
+		} else {
+			result.WriteString(part.Literal)
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResponseMatchers(t *testing.T) {
+	resp := &http.Response{
+		Status:     "201 Created",
+		StatusCode: 201,
+		Header: http.Header{
+			"Location":     []string{"/users/3f2b8c9e-1a2b-4c3d-8e9f-0a1b2c3d4e5f"},
+			"Content-Type": []string{"application/json; charset=utf-8"},
+		},
+	}
+	body := []byte(`{"id": "3f2b8c9e-1a2b-4c3d-8e9f-0a1b2c3d4e5f", "count": 12, "createdAt": "2024-03-05T14:07:09.042Z", "note": null, "url": "/items/12"}`)
+
+	tests := []struct {
+		name     string
+		expected string
+		failures []string
+	}{
+		{"status class", "HTTP/1.1 2xx\n", nil},
+		{"status class mismatch", "HTTP/1.1 4XX\n", []string{"status"}},
+		{"header regex", "HTTP/1.1 201 Created\nLocation: /users/{{$regex ^[0-9a-f-]{36}$}}\n", nil},
+		{"whole header regex", "HTTP/1.1 201 Created\nContent-Type: {{$regex ^application/json}}\n", nil},
+		{"whole header regex unanchored", "HTTP/1.1 201 Created\nContent-Type: {{$regex application/json}}\n", nil},
+		{"whole header regex anchored", "HTTP/1.1 201 Created\nContent-Type: {{$regex ^application/json$}}\n", []string{"header Content-Type"}},
+		{
+			"json matchers",
+			"HTTP/1.1 201 Created\n\n{\"id\": \"{{$regex ^[0-9a-f-]{36}$}}\", \"count\": {{$number}}, \"createdAt\": {{$isoDate}}, \"note\": {{$any}}, \"url\": \"/items/{{$number}}\"}",
+			nil,
+		},
+		{
+			"json matchers mismatch",
+			"HTTP/1.1 201 Created\n\n{\"id\": {{$number}}, \"count\": {{$isoDate}}, \"url\": \"/users/{{$number}}\", \"missing\": {{$any}}}",
+			[]string{"body $.count", "body $.id", "body $.missing", "body $.url"},
+		},
+		{"text body", "HTTP/1.1 201 Created\n\n{\"id\": {{$any}}, \"count\": {{$number}}, {{$any}}", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := parseHTTPResponse(tc.expected)
+			if err != nil {
+				t.Fatalf("got error on function parseHTTPResponse: %v", err)
+			}
+			expected := &http.Response{Status: parsed.StatusText, StatusCode: parsed.StatusCode, Header: make(http.Header)}
+			for key, value := range parsed.ResponseHeaders {
+				expected.Header.Set(key, value)
+			}
+
+			failures := compareResponse(expected, parsed.ResponseBody, resp, body)
+			if len(failures) != len(tc.failures) {
+				t.Fatalf("expected failures %v, Got: %+v", tc.failures, failures)
+			}
+			for i, failure := range failures {
+				if failure.Field != tc.failures[i] {
+					t.Errorf("Incorrect failure [%d]. expected: %s, Got: %s", i, tc.failures[i], failure.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareBodyMatchers(t *testing.T) {
+	tests := []struct {
+		expected string
+		body     string
+		failed   bool
+	}{
+		{"{{$any}}", "hello", false},
+		{"{{$regex ^hel}}", "hello", false},
+		{"{{$regex ^bye}}", "hello", true},
+		{"{{$number}}", "42", false},
+		{`{"id": {{$number}}}`, "hello", true},
+	}
+
+	for _, tc := range tests {
+		failures := compareBody(tc.expected, []byte(tc.body))
+		if (len(failures) > 0) != tc.failed {
+			t.Errorf("Incorrect result of %s against %s, Got: %+v", tc.expected, tc.body, failures)
+		}
+	}
+}
+
+func TestIsHTTPResponseLine(t *testing.T) {
+	tests := map[string]bool{
+		"HTTP/1.1 200 OK":  true,
+		"HTTP/1.1 2xx":     true,
+		"HTTP/2 404":       true,
+		"HTTP/1.1 600 Bad": false,
+		"GET / HTTP/1.1":   false,
+	}
+	for line, expected := range tests {
+		if got := isHTTPResponseLine(line); got != expected {
+			t.Errorf("isHTTPResponseLine(%q) expected: %v, Got: %v", line, expected, got)
+		}
+	}
+}
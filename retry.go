@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// What --retries retries when no `on` is given: transport errors and the statuses of a
+// server that isn't up yet
+var defaultRetryOn = []string{"error", "502", "503", "504"}
+
+// Longest wait between two attempts, however many attempts were made
+const maxRetryDelay = 10 * time.Second
+
+// retryPolicy is how a block is retried: `// @retry 5 200ms on 502,503,connection-refused`
+// or --retries and --retry-backoff
+type retryPolicy struct {
+	Retries  int           // attempts after the first one
+	Backoff  time.Duration // wait before the first retry, doubled for each one after it
+	On       []string      // statuses (502), status classes (5xx), connection-refused, timeout or error
+	Explicit bool          // set by `// @retry`, non-idempotent methods are only retried then
+	Err      error         // the directive couldn't be parsed, reported when the block is sent
+}
+
+// parseRetryDirective parses the value of `// @retry count [backoff] [on conditions]`
+func parseRetryDirective(value string) *retryPolicy {
+	policy := &retryPolicy{Backoff: 200 * time.Millisecond, On: defaultRetryOn, Explicit: true}
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		policy.Err = fmt.Errorf("missing the number of retries")
+		return policy
+	}
+	retries, err := strconv.Atoi(fields[0])
+	if err != nil || retries < 0 {
+		policy.Err = fmt.Errorf("invalid number of retries %q", fields[0])
+		return policy
+	}
+	policy.Retries = retries
+	fields = fields[1:]
+
+	if len(fields) > 0 && fields[0] != "on" {
+		backoff, err := time.ParseDuration(fields[0])
+		if err != nil || backoff < 0 {
+			policy.Err = fmt.Errorf("invalid backoff %q, use a duration like 200ms", fields[0])
+			return policy
+		}
+		policy.Backoff = backoff
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 {
+		if fields[0] != "on" || len(fields) == 1 {
+			policy.Err = fmt.Errorf("expected `on` and what to retry, like on 502,503,connection-refused")
+			return policy
+		}
+		policy.On = strings.FieldsFunc(strings.Join(fields[1:], " "), func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		for _, condition := range policy.On {
+			if !validRetryCondition(condition) {
+				policy.Err = fmt.Errorf("unknown retry condition %q, use a status like 503 or 5xx, connection-refused, timeout or error", condition)
+				return policy
+			}
+		}
+	}
+	return policy
+}
+
+func validRetryCondition(condition string) bool {
+	switch condition {
+	case "error", "connection-refused", "timeout":
+		return true
+	}
+	if len(condition) == 3 && strings.HasSuffix(condition, "xx") {
+		return condition[0] >= '1' && condition[0] <= '5'
+	}
+	status, err := strconv.Atoi(condition)
+	return err == nil && status >= 100 && status <= 599
+}
+
+// blockRetryPolicy is the `// @retry` of the block, otherwise --retries and --retry-backoff
+func blockRetryPolicy(block HTTPBlock, config *Config) retryPolicy {
+	if block.Retry != nil {
+		return *block.Retry
+	}
+	return retryPolicy{
+		Retries: config.Retries,
+		Backoff: time.Duration(config.RetryBackoff) * time.Millisecond,
+		On:      defaultRetryOn,
+	}
+}
+
+// idempotentMethods can be sent twice without changing more than sending them once
+var idempotentMethods = []string{"GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE"}
+
+// shouldRetry tells if an attempt that got resp or err is tried again, non-idempotent
+// methods only with `// @retry`
+func (p retryPolicy) shouldRetry(method string, resp *http.Response, err error) bool {
+	if !p.Explicit && !slices.Contains(idempotentMethods, strings.ToUpper(method)) {
+		return false
+	}
+	refused := err != nil && errors.Is(err, syscall.ECONNREFUSED)
+
+	for _, condition := range p.On {
+		switch {
+		case err != nil:
+			var netErr net.Error
+			timeout := errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+			if condition == "error" || (condition == "connection-refused" && refused) || (condition == "timeout" && timeout) {
+				return true
+			}
+		case resp != nil:
+			status := strconv.Itoa(resp.StatusCode)
+			if condition == status || (strings.HasSuffix(condition, "xx") && condition[0] == status[0]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// delay is the wait before the given retry, 1 for the first one: the backoff doubled for each
+// retry, between half of it and all of it so blocks retried together spread out
+func (p retryPolicy) delay(retry int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < retry && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryDelay)
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// sleepContext waits for d, it returns false when ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
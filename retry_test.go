@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRetryDirective(t *testing.T) {
+	tests := []struct {
+		value   string
+		retries int
+		backoff time.Duration
+		on      string
+		err     string
+	}{
+		{"5 200ms on 502,503,connection-refused", 5, 200 * time.Millisecond, "502 503 connection-refused", ""},
+		{"3", 3, 200 * time.Millisecond, "error 502 503 504", ""},
+		{"2 on 5xx, timeout", 2, 200 * time.Millisecond, "5xx timeout", ""},
+		{"1 2s", 1, 2 * time.Second, "error 502 503 504", ""},
+		{"", 0, 0, "", "missing the number of retries"},
+		{"many", 0, 0, "", "invalid number of retries"},
+		{"3 soon", 0, 0, "", "invalid backoff"},
+		{"3 100ms on", 0, 0, "", "expected `on`"},
+		{"3 on teapot", 0, 0, "", "unknown retry condition"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			policy := parseRetryDirective(tc.value)
+			if tc.err != "" {
+				if policy.Err == nil || !strings.Contains(policy.Err.Error(), tc.err) {
+					t.Errorf("expected error %q, Got: %v", tc.err, policy.Err)
+				}
+				return
+			}
+			if policy.Err != nil {
+				t.Fatalf("got error on function parseRetryDirective: %v", policy.Err)
+			}
+			if policy.Retries != tc.retries || policy.Backoff != tc.backoff || strings.Join(policy.On, " ") != tc.on || !policy.Explicit {
+				t.Errorf("Incorrect policy: %+v", policy)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := retryPolicy{Backoff: 100 * time.Millisecond}
+	for retry, expected := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 20: maxRetryDelay} {
+		for range 20 {
+			if delay := policy.delay(retry); delay < expected/2 || delay > expected {
+				t.Errorf("Incorrect delay of retry %d. expected between %s and %s, Got: %s", retry, expected/2, expected, delay)
+			}
+		}
+	}
+}
+
+func TestSendBlockRetries(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		n := calls[r.URL.Path]
+		mu.Unlock()
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		content  string
+		retries  int
+		outcome  string
+		attempts int
+	}{
+		{"directive", "// @retry 3 1ms on 503\n// @assert status == 200\nGET %s/directive HTTP/1.1", 0, outcomePassed, 3},
+		{"not enough retries", "// @retry 1 1ms on 5xx\n// @assert status == 200\nGET %s/short HTTP/1.1", 0, outcomeFailed, 2},
+		{"status not listed", "// @retry 3 1ms on 502\n// @assert status == 200\nGET %s/listed HTTP/1.1", 0, outcomeFailed, 1},
+		{"flag", "// @assert status == 200\nGET %s/flag HTTP/1.1", 2, outcomePassed, 3},
+		{"flag skips POST", "// @assert status == 200\nPOST %s/post HTTP/1.1", 2, outcomeFailed, 1},
+		{"directive allows POST", "// @retry 2 1ms\n// @assert status == 200\nPOST %s/allowed HTTP/1.1", 0, outcomePassed, 3},
+		{"connection refused", "GET http://127.0.0.1:1/down HTTP/1.1%.0s", 2, outcomeErrored, 3},
+		{"flag skips refused POST", "POST http://127.0.0.1:1/down HTTP/1.1%.0s", 2, outcomeErrored, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{HTTPRequestTimeout: 2000, Retries: tc.retries, RetryBackoff: 1}
+			httpFileContent, err := writeHTTPFiles(t, config, map[string]string{"api.http": fmt.Sprintf(tc.content, server.URL)})
+			if err != nil {
+				t.Fatalf("got error on function processHTTPFiles: %v", err)
+			}
+			result := sendBlock(context.Background(), httpFileContent[0], httpFileContent[0].Blocks[0], config, newRunState())
+			if result.Outcome != tc.outcome || result.Attempts != tc.attempts {
+				t.Errorf("expected %s after %d attempts, Got: %s after %d (%v)", tc.outcome, tc.attempts, result.Outcome, result.Attempts, result.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Exit codes of `lazyrequests run`
+const (
+	exitPassed = 0 // every block passed
+	exitFailed = 1 // a block failed its checks or couldn't be sent, or the server wasn't ready
+	exitError  = 2 // the .http files couldn't be read or parsed
+)
+
+// runSummary counts the outcome of the blocks of a run
+type runSummary struct {
+	Passed    int // sent and every check passed
+	Failed    int // sent but a check failed
+	Errored   int // couldn't be sent: transport errors, missing files, failed pre-request scripts...
+	Skipped   int // `// @note` blocks not confirmed and blocks whose dependencies didn't pass
+	Snapshots int // snapshots written with --snapshot-update
+	Duration  time.Duration
+	Cancelled bool // files changed while watching, the run stopped before the end
+}
+
+func (s runSummary) ok() bool {
+	return s.Failed == 0 && s.Errored == 0
+}
+
+// runOnce is `lazyrequests run`: sends every block once, prints a summary and returns the exit code
+func runOnce(config *Config) int {
+	httpFileContentParsed, err := processHTTPFiles(config)
+	if err != nil {
+		fmt.Printf("%s%v%s\n", C_Red, err, C_Reset)
+		return exitError
+	}
+
+	if !waitForServer(context.Background(), config) {
+		return exitFailed
+	}
+	summary := sendRequests(context.Background(), httpFileContentParsed, config)
+	if !summary.ok() {
+		return exitFailed
+	}
+	return exitPassed
+}
+
+func printSummary(summary runSummary) {
+	color := C_Green
+	if !summary.ok() {
+		color = C_Red
+	}
+	fmt.Printf("%s%d passed, %d failed, %d errored, %d skipped in %s%s\n",
+		color, summary.Passed, summary.Failed, summary.Errored, summary.Skipped, summary.Duration.Round(time.Millisecond), C_Reset)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		content  string
+		exitCode int
+		summary  runSummary
+	}{
+		{"passed", "GET %[1]s/ok HTTP/1.1\n###\n// @assert status == 200\nGET %[1]s/ok HTTP/1.1", exitPassed, runSummary{Passed: 2}},
+		{"failed", "GET %[1]s/ok HTTP/1.1\n###\n// @assert status == 200\nGET %[1]s/missing HTTP/1.1", exitFailed, runSummary{Passed: 1, Failed: 1}},
+		{"errored", "GET http://127.0.0.1:1/down HTTP/1.1", exitFailed, runSummary{Errored: 1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "api.http")
+			if err := os.WriteFile(path, []byte(fmt.Sprintf(tc.content, server.URL)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			config := &Config{HTTPFilePath: path, HTTPRequestTimeout: 2000, Run: true}
+
+			if exitCode := runOnce(config); exitCode != tc.exitCode {
+				t.Errorf("Incorrect exit code. expected: %d, Got: %d", tc.exitCode, exitCode)
+			}
+
+			httpFileContent, err := processHTTPFiles(config)
+			if err != nil {
+				t.Fatalf("got error on function processHTTPFiles: %v", err)
+			}
+			summary := sendRequests(context.Background(), httpFileContent, config)
+			summary.Duration = 0
+			if summary != tc.summary {
+				t.Errorf("Incorrect summary. expected: %+v, Got: %+v", tc.summary, summary)
+			}
+		})
+	}
+
+	if exitCode := runOnce(&Config{HTTPFilePath: filepath.Join(t.TempDir(), "missing.http")}); exitCode != exitError {
+		t.Errorf("Incorrect exit code for a missing file. expected: %d, Got: %d", exitError, exitCode)
+	}
+}
+
+func TestRunOnceWithReportFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.http")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("GET %s/health HTTP/1.1", server.URL)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reporters, err := parseReporters([]string{"junit=" + filepath.Join(dir, "junit.xml")}, "")
+	if err != nil {
+		t.Fatalf("got error on function parseReporters: %v", err)
+	}
+	config := &Config{HTTPFilePath: path, HTTPRequestTimeout: 2000, Run: true, Reporters: reporters}
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		output <- string(out)
+	}()
+	exitCode := runOnce(config)
+	w.Close()
+	os.Stdout = stdout
+	printed := <-output
+
+	if exitCode != exitPassed {
+		t.Errorf("Incorrect exit code. expected: %d, Got: %d", exitPassed, exitCode)
+	}
+	for _, text := range []string{"/health", "1 passed, 0 failed, 0 errored, 0 skipped"} {
+		if !strings.Contains(printed, text) {
+			t.Errorf("missing %q in the terminal output: %q", text, printed)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "junit.xml")); err != nil {
+		t.Errorf("the junit report wasn't written: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Compiled `// @schema` files, compiled again when the file or a file it $refs changes
+var (
+	schemaCacheMu sync.Mutex
+	schemaCache   = make(map[string]cachedSchema)
+)
+
+type cachedSchema struct {
+	modTimes map[string]time.Time // of every file the schema was compiled from
+	schema   *jsonschema.Schema
+}
+
+// changed tells if one of the files of the schema changed since it was compiled
+func (c cachedSchema) changed() bool {
+	for path, modTime := range c.modTimes {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaFileLoader loads the schema files like jsonschema.FileLoader and records their
+// modification times
+type schemaFileLoader struct {
+	jsonschema.FileLoader
+	modTimes map[string]time.Time
+}
+
+func (l *schemaFileLoader) Load(url string) (any, error) {
+	path, err := l.ToFile(url)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	l.modTimes[path] = info.ModTime()
+	return l.FileLoader.Load(url)
+}
+
+var schemaPrinter = message.NewPrinter(language.English)
+
+// loadSchema compiles a JSON Schema file, draft 2020-12 unless its $schema says
+// otherwise (draft-07, 2019-09...)
+func loadSchema(path string) (*jsonschema.Schema, error) {
+	cached, err := compileSchema(path)
+	return cached.schema, err
+}
+
+// schemaFiles returns the files a schema is compiled from, itself and the files it $refs
+func schemaFiles(path string) []string {
+	cached, err := compileSchema(path)
+	if err != nil {
+		return []string{path}
+	}
+	var files []string
+	for file := range cached.modTimes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func compileSchema(path string) (cachedSchema, error) {
+	if _, err := os.Stat(path); err != nil {
+		return cachedSchema{}, err
+	}
+
+	schemaCacheMu.Lock()
+	defer schemaCacheMu.Unlock()
+	if cached, ok := schemaCache[path]; ok && !cached.changed() {
+		return cached, nil
+	}
+
+	loader := &schemaFileLoader{modTimes: make(map[string]time.Time)}
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.UseLoader(jsonschema.SchemeURLLoader{"file": loader})
+	schema, err := compiler.Compile(path)
+	if err != nil {
+		return cachedSchema{}, err
+	}
+	cached := cachedSchema{modTimes: loader.modTimes, schema: schema}
+	schemaCache[path] = cached
+	return cached, nil
+}
+
+// validateSchema checks a response body against the schema of a block,
+// one failure per violation with the JSON pointer of the invalid value
+func validateSchema(path string, body []byte) []assertionFailure {
+	schema, err := loadSchema(path)
+	if err != nil {
+		return []assertionFailure{{Field: "schema", Message: fmt.Sprintf("can't load %s: %v", path, err)}}
+	}
+
+	data, err := decodeJSON(body)
+	if err != nil {
+		return []assertionFailure{{Field: "schema", Expected: "JSON", Got: truncate(strings.TrimSpace(string(body)), 80)}}
+	}
+
+	err = schema.Validate(data)
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []assertionFailure{{Field: "schema", Message: err.Error()}}
+	}
+
+	violations := schemaViolations(validationErr)
+	// Sorted so the violations are always printed in the same order
+	sort.SliceStable(violations, func(i, j int) bool {
+		return jsonPointer(violations[i].InstanceLocation) < jsonPointer(violations[j].InstanceLocation)
+	})
+
+	var failures []assertionFailure
+	for _, violation := range violations {
+		failures = append(failures, assertionFailure{
+			Field:   "schema #" + jsonPointer(violation.InstanceLocation),
+			Message: violation.ErrorKind.LocalizedString(schemaPrinter),
+		})
+	}
+	return failures
+}
+
+// schemaViolations returns the errors that caused err, without the ones grouping others
+func schemaViolations(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var violations []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+	return violations
+}
+
+// jsonPointer joins the tokens of a location as in RFC 6901, ["a/b", "0"] is /a~1b/0
+func jsonPointer(tokens []string) string {
+	var pointer strings.Builder
+	for _, token := range tokens {
+		pointer.WriteString("/")
+		token = strings.ReplaceAll(token, "~", "~0")
+		pointer.WriteString(strings.ReplaceAll(token, "/", "~1"))
+	}
+	return pointer.String()
+}
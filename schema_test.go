@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		body     string
+		failures []string
+	}{
+		{"valid", "user.json", `{"id": 1, "name": "Ada", "roles": ["admin"]}`, nil},
+		{"violations", "user.json", `{"id": 0, "roles": ["admin", "root"]}`, []string{"schema #", "schema #/id", "schema #/roles/1"}},
+		{"draft-07", "user-draft7.json", `{"id": "1", "extra": true}`, []string{"schema #", "schema #/id"}},
+		{"not JSON", "user.json", `<html>`, []string{"schema"}},
+		{"missing schema", "missing.json", `{}`, []string{"schema"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			failures := validateSchema(filepath.Join("http_folder", "fixtures", "schemas", tc.schema), []byte(tc.body))
+			if len(failures) != len(tc.failures) {
+				t.Fatalf("expected failures %v, Got: %+v", tc.failures, failures)
+			}
+			for i, failure := range failures {
+				if failure.Field != tc.failures[i] {
+					t.Errorf("Incorrect failure [%d]. expected: %s, Got: %+v", i, tc.failures[i], failure)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSchemaReloadsChangedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(`{"type": "object"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if failures := validateSchema(path, []byte(`[]`)); len(failures) != 1 {
+		t.Fatalf("expected 1 failure, Got: %+v", failures)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"type": "array"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time changes on coarse filesystems
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if failures := validateSchema(path, []byte(`[]`)); len(failures) != 0 {
+		t.Errorf("expected the changed schema to be used, Got: %+v", failures)
+	}
+}
+
+func TestLoadSchemaReloadsReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schema.json")
+	item := filepath.Join(dir, "item.json")
+	if err := os.WriteFile(path, []byte(`{"$ref": "item.json"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(item, []byte(`{"type": "object"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if failures := validateSchema(path, []byte(`[]`)); len(failures) != 1 {
+		t.Fatalf("expected 1 failure, Got: %+v", failures)
+	}
+	if files := schemaFiles(path); len(files) != 2 || files[0] != item || files[1] != path {
+		t.Errorf("Incorrect schema files: %v", files)
+	}
+
+	if err := os.WriteFile(item, []byte(`{"type": "array"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(item, later, later)
+	if failures := validateSchema(path, []byte(`[]`)); len(failures) != 0 {
+		t.Errorf("expected the changed $ref file to be used, Got: %+v", failures)
+	}
+}
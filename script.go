@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// Scripts are written like in the JetBrains HTTP client:
+//
+//	< {% request.variables.set("id", "42") %}
+//	GET http://localhost:8080/users/{{id}}
+//
+//	> {%
+//	    client.global.set("token", response.body.token);
+//	    client.test("ok", () => client.assert(response.status === 200, "status is not 200"));
+//	%}
+//
+// Pre-request scripts go above the request line and response handlers after the body.
+
+var (
+	// Variables saved with client.global.set, kept for the whole watch session
+	scriptGlobalsMu sync.Mutex
+	scriptGlobals   = make(map[string]string)
+)
+
+// Scripts running longer than this are stopped, so a loop can't block the watcher
+const scriptTimeout = 5 * time.Second
+
+// cutScript reads a `< {% ... %}` or `> {% ... %}` script starting at lines[start], returning
+// its code and the index of the line after it. ok is false when the line doesn't start a script.
+func cutScript(lines []string, start int, marker string) (string, int, bool) {
+	trimmed := strings.TrimSpace(lines[start])
+	if !strings.HasPrefix(trimmed, marker) {
+		return "", start, false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(trimmed, marker))
+	if !strings.HasPrefix(rest, "{%") {
+		return "", start, false
+	}
+	rest = strings.TrimPrefix(rest, "{%")
+
+	var code []string
+	for i := start; i < len(lines); i++ {
+		if i > start {
+			rest = lines[i]
+		}
+		if end := strings.Index(rest, "%}"); end != -1 {
+			code = append(code, rest[:end])
+			return strings.TrimSpace(strings.Join(code, "\n")), i + 1, true
+		}
+		code = append(code, rest)
+	}
+	// Not closed, the rest of the block is the script
+	return strings.TrimSpace(strings.Join(code, "\n")), len(lines), true
+}
+
+// runPreRequestScript runs a `< {% %}` script, returning the variables it set with request.variables.set
+func runPreRequestScript(script string, req HTTPRequest, fileContent HTTPFileContent) (map[string]string, error) {
+	vm, variables, _ := newScriptRuntime(req, fileContent)
+	if err := runScript(vm, script); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// runResponseHandler runs a `> {% %}` script, returning the tests registered with client.test
+func runResponseHandler(script string, req HTTPRequest, fileContent HTTPFileContent, resp *http.Response, body []byte) ([]assertionResult, error) {
+	vm, _, tests := newScriptRuntime(req, fileContent)
+	vm.Set("response", newScriptResponse(vm, resp, body))
+	err := runScript(vm, script)
+	return *tests, err
+}
+
+func runScript(vm *goja.Runtime, script string) error {
+	timer := time.AfterFunc(scriptTimeout, func() {
+		vm.Interrupt(fmt.Sprintf("script stopped after %s", scriptTimeout))
+	})
+	defer timer.Stop()
+
+	_, err := vm.RunString(script)
+	if err != nil {
+		return errors.New(scriptErrorMessage(vm, err))
+	}
+	return nil
+}
+
+// newScriptRuntime returns a JavaScript runtime with the client and request objects
+func newScriptRuntime(req HTTPRequest, fileContent HTTPFileContent) (*goja.Runtime, map[string]string, *[]assertionResult) {
+	vm := goja.New()
+	variables := make(map[string]string)
+	tests := &[]assertionResult{}
+
+	global := vm.NewObject()
+	global.Set("set", func(name string, value goja.Value) {
+		scriptGlobalsMu.Lock()
+		scriptGlobals[name] = scriptString(vm, value)
+		scriptGlobalsMu.Unlock()
+	})
+	global.Set("get", func(name string) goja.Value {
+		scriptGlobalsMu.Lock()
+		defer scriptGlobalsMu.Unlock()
+		if value, ok := scriptGlobals[name]; ok {
+			return vm.ToValue(value)
+		}
+		return goja.Null()
+	})
+	global.Set("isEmpty", func() bool {
+		scriptGlobalsMu.Lock()
+		defer scriptGlobalsMu.Unlock()
+		return len(scriptGlobals) == 0
+	})
+	global.Set("clear", func(name string) {
+		scriptGlobalsMu.Lock()
+		delete(scriptGlobals, name)
+		scriptGlobalsMu.Unlock()
+	})
+	global.Set("clearAll", func() {
+		scriptGlobalsMu.Lock()
+		scriptGlobals = make(map[string]string)
+		scriptGlobalsMu.Unlock()
+	})
+
+	client := vm.NewObject()
+	client.Set("global", global)
+	client.Set("test", func(name string, fn goja.Value) {
+		result := assertionResult{Name: name, Passed: true}
+		if call, ok := goja.AssertFunction(fn); ok {
+			if _, err := call(goja.Undefined()); err != nil {
+				// Stopped scripts stop the tests too
+				var interrupted *goja.InterruptedError
+				if errors.As(err, &interrupted) {
+					panic(err)
+				}
+				result.Passed = false
+				result.Got = scriptErrorMessage(vm, err)
+			}
+		}
+		*tests = append(*tests, result)
+	})
+	client.Set("assert", func(condition goja.Value, message goja.Value) {
+		if condition.ToBoolean() {
+			return
+		}
+		text := "Assertion failed"
+		if !goja.IsUndefined(message) && !goja.IsNull(message) {
+			text = message.String()
+		}
+		errorConstructor, _ := goja.AssertConstructor(vm.Get("Error"))
+		jsError, err := errorConstructor(nil, vm.ToValue(text))
+		if err != nil {
+			panic(vm.NewGoError(errors.New(text)))
+		}
+		panic(jsError)
+	})
+	client.Set("log", func(call goja.FunctionCall) goja.Value {
+		var parts []string
+		for _, arg := range call.Arguments {
+			parts = append(parts, scriptString(vm, arg))
+		}
+		fmt.Printf("%s    %s%s\n", C_Gray, strings.Join(parts, " "), C_Reset)
+		return goja.Undefined()
+	})
+	vm.Set("client", client)
+
+	request := vm.NewObject()
+	request.Set("method", req.Method)
+	url := vm.NewObject()
+	url.Set("getRaw", func() string { return req.Url })
+	request.Set("url", url)
+	body := vm.NewObject()
+	body.Set("getRaw", func() string { return req.Body })
+	request.Set("body", body)
+	headers := vm.NewObject()
+	headers.Set("findByName", func(name string) goja.Value {
+		for key, value := range req.Headers {
+			if strings.EqualFold(key, name) {
+				return vm.ToValue(value)
+			}
+		}
+		return goja.Null()
+	})
+	request.Set("headers", headers)
+	environment := vm.NewObject()
+	environment.Set("get", func(name string) goja.Value {
+		if value, ok := fileContent.EnvironmentVariables[name]; ok {
+			return vm.ToValue(value)
+		}
+		return goja.Null()
+	})
+	request.Set("environment", environment)
+	requestVariables := vm.NewObject()
+	requestVariables.Set("set", func(name string, value goja.Value) {
+		variables[name] = scriptString(vm, value)
+	})
+	requestVariables.Set("get", func(name string) goja.Value {
+		if value, ok := variables[name]; ok {
+			return vm.ToValue(value)
+		}
+		return goja.Null()
+	})
+	request.Set("variables", requestVariables)
+	vm.Set("request", request)
+
+	return vm, variables, tests
+}
+
+// newScriptResponse is the response object of the response handlers,
+// JSON bodies are parsed and the others are strings
+func newScriptResponse(vm *goja.Runtime, resp *http.Response, body []byte) *goja.Object {
+	response := vm.NewObject()
+	response.Set("status", resp.StatusCode)
+
+	var parsed goja.Value = vm.ToValue(string(body))
+	if parse, ok := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse")); ok {
+		if value, err := parse(goja.Undefined(), vm.ToValue(string(body))); err == nil {
+			parsed = value
+		}
+	}
+	response.Set("body", parsed)
+
+	headers := vm.NewObject()
+	headers.Set("valueOf", func(name string) goja.Value {
+		if value := resp.Header.Get(name); value != "" {
+			return vm.ToValue(value)
+		}
+		return goja.Null()
+	})
+	headers.Set("valuesOf", func(name string) []string {
+		return resp.Header.Values(name)
+	})
+	response.Set("headers", headers)
+
+	mimeType, params, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	contentType := vm.NewObject()
+	contentType.Set("mimeType", strings.TrimSpace(mimeType))
+	charset := ""
+	for _, param := range strings.Split(params, ";") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.EqualFold(key, "charset") {
+			charset = strings.Trim(value, `"`)
+		}
+	}
+	contentType.Set("charset", charset)
+	response.Set("contentType", contentType)
+	return response
+}
+
+// scriptString turns a value set from a script into the text used for {{variables}}, objects as JSON
+func scriptString(vm *goja.Runtime, value goja.Value) string {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return ""
+	}
+	if object, ok := value.(*goja.Object); ok && object.ClassName() != "String" {
+		if stringify, ok := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify")); ok {
+			if encoded, err := stringify(goja.Undefined(), value); err == nil && !goja.IsUndefined(encoded) {
+				return encoded.String()
+			}
+		}
+	}
+	return value.String()
+}
+
+// scriptErrorMessage returns the message of a thrown Error, without the stack
+func scriptErrorMessage(vm *goja.Runtime, err error) string {
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		if object, ok := exception.Value().(*goja.Object); ok {
+			if message := object.Get("message"); message != nil && !goja.IsUndefined(message) {
+				return message.String()
+			}
+		}
+		return exception.Value().String()
+	}
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		return fmt.Sprint(interrupted.Value())
+	}
+	return err.Error()
+}
+
+// resolveScriptVariables replaces the {{variables}} set by scripts, the ones of
+// request.variables.set first and then the ones of client.global.set
+func resolveScriptVariables(req HTTPRequest, variables map[string]string) HTTPRequest {
+	scriptGlobalsMu.Lock()
+	if len(variables) == 0 && len(scriptGlobals) == 0 {
+		scriptGlobalsMu.Unlock()
+		return req
+	}
+	globals := make(map[string]string, len(scriptGlobals))
+	for name, value := range scriptGlobals {
+		globals[name] = value
+	}
+	scriptGlobalsMu.Unlock()
+
+	return mapRequestText(req, func(text string) string {
+		return rePlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+			name := strings.TrimSpace(match[2 : len(match)-2])
+			if value, ok := variables[name]; ok {
+				return value
+			}
+			if value, ok := globals[name]; ok {
+				return value
+			}
+			return match
+		})
+	})
+}
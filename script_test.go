@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRunResponseHandler(t *testing.T) {
+	defer func() { scriptGlobals = make(map[string]string) }()
+
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+	}
+	body := []byte(`{"token": "abc", "user": {"id": 7, "roles": ["admin"]}}`)
+	script := `
+		client.global.set("token", response.body.token);
+		client.global.set("user", response.body.user);
+		client.test("status", () => client.assert(response.status === 200));
+		client.test("charset", () => client.assert(response.contentType.charset === "utf-8"));
+		client.test("role", () => client.assert(response.body.user.roles[0] === "root", "not root"));
+		client.test("header", function () {
+			client.assert(response.headers.valueOf("content-type").startsWith("application/json"));
+		});
+	`
+	tests, err := runResponseHandler(script, HTTPRequest{Method: "POST"}, HTTPFileContent{}, resp, body)
+	if err != nil {
+		t.Fatalf("got error on function runResponseHandler: %v", err)
+	}
+
+	expected := []assertionResult{
+		{Name: "status", Passed: true},
+		{Name: "charset", Passed: true},
+		{Name: "role", Passed: false, Got: "not root"},
+		{Name: "header", Passed: true},
+	}
+	if len(tests) != len(expected) {
+		t.Fatalf("expected tests %v, Got: %v", expected, tests)
+	}
+	for i := range expected {
+		if tests[i] != expected[i] {
+			t.Errorf("Incorrect test [%d]. expected: %+v, Got: %+v", i, expected[i], tests[i])
+		}
+	}
+
+	if scriptGlobals["token"] != "abc" || scriptGlobals["user"] != `{"id":7,"roles":["admin"]}` {
+		t.Errorf("Incorrect globals: %v", scriptGlobals)
+	}
+
+	req := resolveScriptVariables(HTTPRequest{Url: "/users/{{id}}", Headers: map[string]string{"Authorization": "Bearer {{token}}"}}, map[string]string{"id": "7"})
+	if req.Url != "/users/7" || req.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("Incorrect resolved request: %+v", req)
+	}
+}
+
+func TestRunPreRequestScript(t *testing.T) {
+	req := HTTPRequest{Method: "GET", Url: "/users", Headers: map[string]string{"X-Tenant": "acme"}}
+	fileContent := HTTPFileContent{EnvironmentVariables: map[string]string{"region": "eu"}}
+
+	variables, err := runPreRequestScript(`
+		request.variables.set("tenant", request.headers.findByName("x-tenant") + "-" + request.environment.get("region"));
+		request.variables.set("page", 2);
+	`, req, fileContent)
+	if err != nil {
+		t.Fatalf("got error on function runPreRequestScript: %v", err)
+	}
+	if variables["tenant"] != "acme-eu" || variables["page"] != "2" {
+		t.Errorf("Incorrect variables: %v", variables)
+	}
+
+	if _, err := runPreRequestScript(`throw new Error("no tenant")`, req, fileContent); err == nil || err.Error() != "no tenant" {
+		t.Errorf("expected the thrown error, Got: %v", err)
+	}
+}
+
+func TestCutScript(t *testing.T) {
+	lines := strings.Split("> {% client.log(1) %}\n> {%\n  a();\n  b();\n%}\nGET /", "\n")
+
+	code, next, ok := cutScript(lines, 0, ">")
+	if !ok || code != "client.log(1)" || next != 1 {
+		t.Errorf("Incorrect one line script: %q %d %v", code, next, ok)
+	}
+	code, next, ok = cutScript(lines, 1, ">")
+	if !ok || code != "a();\n  b();" || next != 5 {
+		t.Errorf("Incorrect multi line script: %q %d %v", code, next, ok)
+	}
+	if _, _, ok := cutScript(lines, 5, ">"); ok {
+		t.Errorf("the request line isn't a script")
+	}
+}
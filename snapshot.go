@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Snapshots are saved in this directory next to the .http file
+const snapshotDir = "__snapshots__"
+
+// Headers saved in snapshots, the others change too often (Date, Content-Length...)
+var snapshotHeaders = []string{"Content-Type", "Location"}
+
+// Replaces the values of `// @snapshot-ignore $.createdAt`
+const snapshotIgnored = "<ignored>"
+
+// snapshotPath is __snapshots__/<file>.http.<name or id>.snap, naming the block
+// with `// @name` keeps its snapshot when blocks are added above it
+func snapshotPath(httpFilePath string, block HTTPBlock) string {
+	key := block.Name
+	if key == "" {
+		key = strconv.Itoa(block.ID)
+	}
+	return filepath.Join(filepath.Dir(httpFilePath), snapshotDir, filepath.Base(httpFilePath)+"."+key+".snap")
+}
+
+// snapshotText normalizes a response: status line, the snapshotHeaders and the body,
+// JSON bodies are indented with sorted keys and without the ignored values
+func snapshotText(resp *http.Response, body []byte, ignore []string) (string, error) {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s %s\n", resp.Proto, resp.Status)
+	for _, name := range snapshotHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			fmt.Fprintf(&text, "%s: %s\n", name, strings.Join(values, ", "))
+		}
+	}
+	text.WriteString("\n")
+
+	data, err := decodeJSON(body)
+	if err != nil {
+		text.WriteString(strings.TrimSpace(string(body)))
+		text.WriteString("\n")
+		return text.String(), nil
+	}
+
+	for _, path := range ignore {
+		steps, err := parseJSONPath(path)
+		if err != nil {
+			return "", fmt.Errorf("@snapshot-ignore: %w", err)
+		}
+		replaceJSONPath(data, steps, snapshotIgnored)
+	}
+
+	pretty, err := prettyJSON(data)
+	if err != nil {
+		return "", err
+	}
+	text.WriteString(pretty)
+	return text.String(), nil
+}
+
+// prettyJSON indents decoded JSON with sorted keys, so the same data is always the same text
+func prettyJSON(data any) (string, error) {
+	var pretty bytes.Buffer
+	encoder := json.NewEncoder(&pretty)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return "", err
+	}
+	return pretty.String(), nil
+}
+
+// saveSnapshot writes the snapshot of a response, used with --snapshot-update
+func saveSnapshot(path string, resp *http.Response, body []byte, ignore []string) error {
+	text, err := snapshotText(resp, body, ignore)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(text), 0o644)
+}
+
+// compareSnapshot diffs a response against its saved snapshot, blocks without snapshot aren't checked
+func compareSnapshot(path string, resp *http.Response, body []byte, ignore []string) ([]assertionFailure, []string) {
+	saved, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return []assertionFailure{{Field: "snapshot", Message: err.Error()}}, nil
+	}
+
+	text, err := snapshotText(resp, body, ignore)
+	if err != nil {
+		return []assertionFailure{{Field: "snapshot", Message: err.Error()}}, nil
+	}
+
+	name := filepath.Join(snapshotDir, filepath.Base(path))
+	diff := unifiedDiff(name, "response", strings.ReplaceAll(string(saved), "\r\n", "\n"), text)
+	if len(diff) == 0 {
+		return nil, nil
+	}
+	return []assertionFailure{{Field: "snapshot", Message: "response differs from " + name + ", run with --snapshot-update to accept it"}}, diff
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotText(t *testing.T) {
+	resp := &http.Response{
+		Proto:  "HTTP/1.1",
+		Status: "201 Created",
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+			"Date":         []string{"Mon, 04 Mar 2024 10:00:00 GMT"},
+		},
+	}
+	body := []byte(`{"name":"<Ada>","id":7,"createdAt":"2024-03-04","items":[{"at":1},{"at":2}]}`)
+
+	text, err := snapshotText(resp, body, []string{"$.createdAt", "$.items[*].at", "$.missing"})
+	if err != nil {
+		t.Fatalf("got error on function snapshotText: %v", err)
+	}
+	expected := `HTTP/1.1 201 Created
+Content-Type: application/json
+
+{
+  "createdAt": "<ignored>",
+  "id": 7,
+  "items": [
+    {
+      "at": "<ignored>"
+    },
+    {
+      "at": "<ignored>"
+    }
+  ],
+  "name": "<Ada>"
+}
+`
+	if text != expected {
+		t.Errorf("Incorrect snapshot.\nexpected: %s\nGot:      %s", expected, text)
+	}
+
+	if _, err := snapshotText(resp, body, []string{"createdAt"}); err == nil {
+		t.Errorf("expected an error for an invalid JSONPath")
+	}
+}
+
+func TestCompareSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	block := HTTPBlock{ID: 3, Name: "login"}
+	path := snapshotPath(filepath.Join(dir, "users.http"), block)
+	if path != filepath.Join(dir, snapshotDir, "users.http.login.snap") {
+		t.Errorf("Incorrect snapshot path: %s", path)
+	}
+
+	resp := &http.Response{Proto: "HTTP/1.1", Status: "200 OK", Header: http.Header{}}
+	if failures, diff := compareSnapshot(path, resp, []byte(`{"token":"a"}`), nil); failures != nil || diff != nil {
+		t.Errorf("blocks without snapshot shouldn't fail: %v %v", failures, diff)
+	}
+
+	if err := saveSnapshot(path, resp, []byte(`{"token":"a"}`), nil); err != nil {
+		t.Fatalf("got error on function saveSnapshot: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("snapshot not written: %v", err)
+	}
+	if failures, _ := compareSnapshot(path, resp, []byte(`{ "token": "a" }`), nil); failures != nil {
+		t.Errorf("same response should match: %v", failures)
+	}
+
+	failures, diff := compareSnapshot(path, resp, []byte(`{"token":"b"}`), nil)
+	if len(failures) != 1 || failures[0].Field != "snapshot" {
+		t.Fatalf("expected a snapshot failure, Got: %v", failures)
+	}
+	if !strings.Contains(strings.Join(diff, "\n"), "-  \"token\": \"a\"\n+  \"token\": \"b\"") {
+		t.Errorf("Incorrect diff:\n%s", strings.Join(diff, "\n"))
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj"
+	to := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk"
+	expected := []string{
+		"--- old",
+		"+++ new",
+		"@@ -1,7 +1,7 @@",
+		" a", " b", " c", "-d", "+D", " e", " f", " g",
+		"@@ -8,3 +8,4 @@",
+		" h", " i", " j", "+k",
+	}
+	diff := unifiedDiff("old", "new", from, to)
+	if strings.Join(diff, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Incorrect diff.\nexpected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(diff, "\n"))
+	}
+	if diff := unifiedDiff("old", "new", from, from); diff != nil {
+		t.Errorf("same texts shouldn't have a diff: %v", diff)
+	}
+}
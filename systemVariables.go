@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Placeholders like {{$guid}} or {{$datetime "DD-MM-YYYY" 1 d}}
+var reSystemVariable = regexp.MustCompile(`\{\{\s*\$(\w+)([^}]*?)\s*\}\}`)
+
+// resolveSystemVariables returns a copy of req with the system variables replaced,
+// they are evaluated every time the request is sent so each run gets new values
+func resolveSystemVariables(req HTTPRequest, fileContent HTTPFileContent) (HTTPRequest, []error) {
+	var errs []error
+	resolved := mapRequestText(req, func(text string) string {
+		return reSystemVariable.ReplaceAllStringFunc(text, func(match string) string {
+			parts := reSystemVariable.FindStringSubmatch(match)
+			value, err := evaluateSystemVariable(parts[1], splitVariableArgs(parts[2]), fileContent)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", match, err))
+				return match
+			}
+			return value
+		})
+	})
+	return resolved, errs
+}
+
+// unresolvedVariables reports the placeholders left in req that no resolver
+// recognised, the ones they recognised already reported their own errors
+func unresolvedVariables(req HTTPRequest) []error {
+	texts := []string{req.Url, req.Body}
+	for _, value := range req.Headers {
+		texts = append(texts, value)
+	}
+
+	var errs []error
+	for _, text := range texts {
+		for _, match := range rePlaceholder.FindAllString(text, -1) {
+			if reSystemVariable.MatchString(match) || reRequestVariable.MatchString(match) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s: variable is not defined", match))
+		}
+	}
+	return errs
+}
+
+func evaluateSystemVariable(name string, args []string, fileContent HTTPFileContent) (string, error) {
+	switch name {
+	case "guid":
+		return newGUID()
+	case "randomInt":
+		if len(args) != 2 {
+			return "", fmt.Errorf("usage: {{$randomInt min max}}")
+		}
+		min, err := strconv.Atoi(args[0])
+		if err != nil {
+			return "", fmt.Errorf("invalid min %q", args[0])
+		}
+		max, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", fmt.Errorf("invalid max %q", args[1])
+		}
+		if max <= min {
+			return "", fmt.Errorf("max must be greater than min")
+		}
+		return strconv.Itoa(min + mathrand.Intn(max-min)), nil
+	case "timestamp":
+		t, err := applyTimeOffset(time.Now(), args)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "datetime", "localDatetime":
+		if len(args) == 0 {
+			return "", fmt.Errorf("usage: {{$%s rfc1123|iso8601|\"custom format\" [offset option]}}", name)
+		}
+		t, err := applyTimeOffset(time.Now(), args[1:])
+		if err != nil {
+			return "", err
+		}
+		if name == "datetime" {
+			t = t.UTC()
+		}
+		return formatDatetime(t, args[0], name == "localDatetime"), nil
+	case "processEnv":
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: {{$processEnv [%%]envVarName}}")
+		}
+		envName, err := indirectVariableName(args[0], fileContent)
+		if err != nil {
+			return "", err
+		}
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", envName)
+		}
+		return value, nil
+	case "dotenv":
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: {{$dotenv [%%]variableName}}")
+		}
+		varName, err := indirectVariableName(args[0], fileContent)
+		if err != nil {
+			return "", err
+		}
+		path := filepath.Join(filepath.Dir(fileContent.FilePath), ".env")
+		values, err := readDotenvFile(path)
+		if err != nil {
+			return "", err
+		}
+		value, ok := values[varName]
+		if !ok {
+			return "", fmt.Errorf("%s is not defined in %s", varName, path)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown system variable $%s", name)
+}
+
+// splitVariableArgs splits the arguments of a system variable, keeping quoted ones together
+func splitVariableArgs(text string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// indirectVariableName resolves `%name` to the value of `name` in the selected environment
+func indirectVariableName(name string, fileContent HTTPFileContent) (string, error) {
+	if !strings.HasPrefix(name, "%") {
+		return name, nil
+	}
+	value, ok := fileContent.EnvironmentVariables[name[1:]]
+	if !ok {
+		return "", fmt.Errorf("%s is not defined in the selected environment", name[1:])
+	}
+	return value, nil
+}
+
+func newGUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// applyTimeOffset moves t by an optional `offset option` pair like `-3 d`
+func applyTimeOffset(t time.Time, args []string) (time.Time, error) {
+	if len(args) == 0 {
+		return t, nil
+	}
+	if len(args) != 2 {
+		return t, fmt.Errorf("offset must be a number and a unit like `-1 d`")
+	}
+	offset, err := strconv.Atoi(args[0])
+	if err != nil {
+		return t, fmt.Errorf("invalid offset %q", args[0])
+	}
+
+	switch args[1] {
+	case "y":
+		return t.AddDate(offset, 0, 0), nil
+	case "M":
+		return t.AddDate(0, offset, 0), nil
+	case "w":
+		return t.AddDate(0, 0, 7*offset), nil
+	case "d":
+		return t.AddDate(0, 0, offset), nil
+	case "h":
+		return t.Add(time.Duration(offset) * time.Hour), nil
+	case "m":
+		return t.Add(time.Duration(offset) * time.Minute), nil
+	case "s":
+		return t.Add(time.Duration(offset) * time.Second), nil
+	case "ms":
+		return t.Add(time.Duration(offset) * time.Millisecond), nil
+	}
+	return t, fmt.Errorf("invalid offset unit %q, use y, M, w, d, h, m, s or ms", args[1])
+}
+
+// formatDatetime formats t as rfc1123, iso8601 or a custom Day.js style format like "YYYY-MM-DD HH:mm:ss"
+func formatDatetime(t time.Time, format string, local bool) string {
+	switch format {
+	case "rfc1123":
+		if local {
+			return t.Format(time.RFC1123Z)
+		}
+		return t.Format("Mon, 02 Jan 2006 15:04:05 GMT")
+	case "iso8601":
+		return t.Format("2006-01-02T15:04:05.000Z07:00")
+	}
+
+	// Longest tokens first so YYYY is not read as two YY
+	tokens := []struct {
+		token  string
+		layout string
+	}{
+		{"YYYY", "2006"}, {"YY", "06"},
+		{"MMMM", "January"}, {"MMM", "Jan"}, {"MM", "01"}, {"M", "1"},
+		{"dddd", "Monday"}, {"ddd", "Mon"},
+		{"DD", "02"}, {"D", "2"},
+		{"HH", "15"}, {"hh", "03"}, {"h", "3"},
+		{"mm", "04"}, {"m", "4"},
+		{"ss", "05"}, {"s", "5"},
+		{"A", "PM"}, {"a", "pm"},
+		{"ZZ", "-0700"}, {"Z", "-07:00"},
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(format); {
+		// [text] is copied as it is
+		if format[i] == '[' {
+			if end := strings.IndexByte(format[i:], ']'); end != -1 {
+				result.WriteString(format[i+1 : i+end])
+				i += end + 1
+				continue
+			}
+		}
+		switch {
+		case strings.HasPrefix(format[i:], "SSS"):
+			result.WriteString(fmt.Sprintf("%03d", t.Nanosecond()/int(time.Millisecond)))
+			i += 3
+			continue
+		case strings.HasPrefix(format[i:], "H") && !strings.HasPrefix(format[i:], "HH"):
+			result.WriteString(strconv.Itoa(t.Hour()))
+			i++
+			continue
+		}
+		matched := false
+		for _, tk := range tokens {
+			if strings.HasPrefix(format[i:], tk.token) {
+				result.WriteString(t.Format(tk.layout))
+				i += len(tk.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			result.WriteByte(format[i])
+			i++
+		}
+	}
+	return result.String()
+}
+
+// readDotenvFile reads KEY=VALUE lines from a .env file
+func readDotenvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no .env file found at %s", path)
+		}
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestResolveSystemVariables(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("# secrets\nAPI_KEY=\"dotenv-key\"\nexport REGION=eu\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LAZYREQUESTS_TEST_TOKEN", "process-token")
+	fileContent := HTTPFileContent{
+		FilePath:             filepath.Join(dir, "requests.http"),
+		EnvironmentVariables: map[string]string{"tokenVar": "LAZYREQUESTS_TEST_TOKEN"},
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		pattern string
+		errors  int
+	}{
+		{"guid", "{{$guid}}", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, 0},
+		{"randomInt", "{{$randomInt 5 6}}", `^5$`, 0},
+		{"timestamp", "{{$timestamp}}", `^\d{10,}$`, 0},
+		{"datetime iso8601", "{{$datetime iso8601}}", `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}Z$`, 0},
+		{"datetime rfc1123", "{{$datetime rfc1123 1 d}}", `GMT$`, 0},
+		{"datetime custom", `{{$datetime "YYYY-MM-DD"}}`, `^\d{4}-\d{2}-\d{2}$`, 0},
+		{"processEnv", "{{$processEnv LAZYREQUESTS_TEST_TOKEN}}", `^process-token$`, 0},
+		{"processEnv indirect", "{{$processEnv %tokenVar}}", `^process-token$`, 0},
+		{"processEnv missing", "{{$processEnv LAZYREQUESTS_TEST_MISSING}}", `^\{\{\$processEnv`, 1},
+		{"dotenv", "{{$dotenv API_KEY}}-{{$dotenv REGION}}", `^dotenv-key-eu$`, 0},
+		{"unknown", "{{$nope}}", `^\{\{\$nope\}\}$`, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := HTTPRequest{Url: tc.text, Headers: map[string]string{}}
+			resolved, errs := resolveSystemVariables(req, fileContent)
+			if !regexp.MustCompile(tc.pattern).MatchString(resolved.Url) {
+				t.Errorf("expected to match: %s, Got: %s", tc.pattern, resolved.Url)
+			}
+			if len(errs) != tc.errors {
+				t.Errorf("expected %d errors, Got: %v", tc.errors, errs)
+			}
+		})
+	}
+}
+
+func TestFormatDatetime(t *testing.T) {
+	date := time.Date(2024, time.March, 5, 14, 7, 9, 42*int(time.Millisecond), time.UTC)
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"rfc1123", "Tue, 05 Mar 2024 14:07:09 GMT"},
+		{"iso8601", "2024-03-05T14:07:09.042Z"},
+		{"YYYY-MM-DD HH:mm:ss.SSS", "2024-03-05 14:07:09.042"},
+		{"D/M/YY h:m A", "5/3/24 2:7 PM"},
+		{"dddd [at] H", "Tuesday at 14"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			got := formatDatetime(date, tc.format, false)
+			if got != tc.expected {
+				t.Errorf("expected: %s, Got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnresolvedVariables(t *testing.T) {
+	req := HTTPRequest{
+		Url:     "http://localhost/{{missing}}/{{$guid}}",
+		Headers: map[string]string{"Authorization": "Bearer {{login.response.body.$.token}}"},
+		Body:    `{"id": {{id}}}`,
+	}
+	errs := unresolvedVariables(req)
+	if len(errs) != 2 {
+		t.Errorf("expected 2 errors, Got: %d %v", len(errs), errs)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// terminalInput reads the lines typed in the terminal from a single goroutine and
+// routes them: to the question being asked when there's one, otherwise to onCommand
+type terminalInput struct {
+	once      sync.Once
+	asking    sync.Mutex // one question at a time
+	mu        sync.Mutex
+	answer    chan string // set while a question waits for its answer
+	closed    bool        // stdin reached EOF, nobody can answer anymore
+	onCommand func(line string)
+}
+
+var terminal = &terminalInput{}
+
+var errNoTerminalInput = errors.New("no terminal input to answer")
+
+// stdinIsTerminal tells if someone can type answers, it's false when stdin is a pipe or a file
+var stdinIsTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (t *terminalInput) start() {
+	t.once.Do(func() {
+		go t.read()
+	})
+}
+
+func (t *terminalInput) read() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		t.mu.Lock()
+		answer := t.answer
+		t.answer = nil
+		onCommand := t.onCommand
+		t.mu.Unlock()
+
+		if answer != nil {
+			answer <- line
+			continue
+		}
+		if onCommand != nil {
+			// Commands may ask questions themselves, so they can't block the reader
+			go onCommand(line)
+		}
+	}
+
+	t.mu.Lock()
+	t.closed = true
+	if t.answer != nil {
+		close(t.answer)
+		t.answer = nil
+	}
+	t.mu.Unlock()
+}
+
+// setCommandHandler receives the lines typed while no question is asked
+func (t *terminalInput) setCommandHandler(onCommand func(line string)) {
+	t.mu.Lock()
+	t.onCommand = onCommand
+	t.mu.Unlock()
+	t.start()
+}
+
+// ask prints the question and waits for the next line typed in the terminal, or until ctx is done
+func (t *terminalInput) ask(ctx context.Context, question string) (string, error) {
+	t.start()
+	t.asking.Lock()
+	defer t.asking.Unlock()
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return "", errNoTerminalInput
+	}
+	answer := make(chan string, 1)
+	t.answer = answer
+	t.mu.Unlock()
+
+	fmt.Print(question)
+	select {
+	case line, ok := <-answer:
+		if !ok {
+			return "", errNoTerminalInput
+		}
+		return line, nil
+	case <-ctx.Done():
+		// The next line goes to the commands again
+		t.mu.Lock()
+		if t.answer == answer {
+			t.answer = nil
+		}
+		t.mu.Unlock()
+		fmt.Println()
+		return "", ctx.Err()
+	}
+}
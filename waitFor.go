@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// parseWaitTarget splits --wait-for into how it's polled: an http(s) URL is requested,
+// host:port or tcp://host:port is dialed
+func parseWaitTarget(target string) (network string, address string, err error) {
+	if scheme, rest, ok := strings.Cut(target, "://"); ok {
+		switch strings.ToLower(scheme) {
+		case "http", "https":
+			if _, err := url.ParseRequestURI(target); err != nil {
+				return "", "", fmt.Errorf("--wait-for %s: %w", target, err)
+			}
+			return "http", target, nil
+		case "tcp":
+			target = rest
+		default:
+			return "", "", fmt.Errorf("--wait-for %s: use an http(s) URL, tcp://host:port or host:port", target)
+		}
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return "", "", fmt.Errorf("--wait-for %s: %w", target, err)
+	}
+	return "tcp", target, nil
+}
+
+// waitForReady polls the target every interval until it answers: a 2xx or 3xx for URLs, an
+// accepted connection for ports. It gives up after timeout or once ctx is done.
+func waitForReady(ctx context.Context, target string, timeout, interval time.Duration) error {
+	network, address, err := parseWaitTarget(target)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	poll := func() error {
+		if network == "tcp" {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%s answered %s", address, resp.Status)
+		}
+		return nil
+	}
+
+	for {
+		lastErr := poll()
+		if lastErr == nil {
+			return nil
+		}
+		if !sleepContext(ctx, interval) {
+			if ctx.Err() == context.Canceled {
+				return ctx.Err()
+			}
+			return fmt.Errorf("server not ready after %s: %v", timeout, lastErr)
+		}
+	}
+}
+
+// waitForServer waits for --wait-for before a run, it tells if the requests can be sent
+func waitForServer(ctx context.Context, config *Config) bool {
+	if config.WaitFor == "" {
+		return true
+	}
+	timeout := time.Duration(config.WaitForTimeout) * time.Millisecond
+	interval := time.Duration(config.WaitForInterval) * time.Millisecond
+
+	start := time.Now()
+	fmt.Printf("%swaiting for %s...%s\n", C_Gray, config.WaitFor, C_Reset)
+	err := waitForReady(ctx, config.WaitFor, timeout, interval)
+	switch {
+	case ctx.Err() != nil:
+		return false
+	case err != nil:
+		fmt.Printf("%s%v%s\n", C_Red, err, C_Reset)
+		return false
+	}
+	logVerbose(config, "%s ready after %s", config.WaitFor, time.Since(start).Round(time.Millisecond))
+	return true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseWaitTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		network string
+		address string
+	}{
+		{"http://localhost:8080/healthz", "http", "http://localhost:8080/healthz"},
+		{"https://api.example.com/ready", "http", "https://api.example.com/ready"},
+		{"tcp://localhost:5432", "tcp", "localhost:5432"},
+		{"localhost:5432", "tcp", "localhost:5432"},
+		{"ftp://localhost:21", "", ""},
+		{"localhost", "", ""},
+	}
+
+	for _, tc := range tests {
+		network, address, err := parseWaitTarget(tc.target)
+		if tc.network == "" {
+			if err == nil {
+				t.Errorf("expected an error for %s", tc.target)
+			}
+			continue
+		}
+		if err != nil || network != tc.network || address != tc.address {
+			t.Errorf("Incorrect target %s. expected: %s %s, Got: %s %s %v", tc.target, tc.network, tc.address, network, address, err)
+		}
+	}
+}
+
+func TestWaitForReady(t *testing.T) {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	if err := waitForReady(context.Background(), server.URL+"/healthz", 2*time.Second, 5*time.Millisecond); err != nil {
+		t.Errorf("got error on function waitForReady: %v", err)
+	}
+	if polls.Load() != 3 {
+		t.Errorf("expected 3 polls, Got: %d", polls.Load())
+	}
+
+	if err := waitForReady(context.Background(), strings.TrimPrefix(server.URL, "http://"), time.Second, 5*time.Millisecond); err != nil {
+		t.Errorf("got error on function waitForReady for the port: %v", err)
+	}
+
+	// A port nobody listens on anymore
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	err = waitForReady(context.Background(), address, 50*time.Millisecond, 5*time.Millisecond)
+	if err == nil || !strings.HasPrefix(err.Error(), "server not ready after 50ms") {
+		t.Errorf("expected a not ready error, Got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := waitForReady(ctx, address, time.Second, 5*time.Millisecond); err != context.Canceled {
+		t.Errorf("expected the run to be cancelled, Got: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// runController makes sure only one run prints at a time while watching: a new run cancels
+// the one in flight and waits for it to stop before it starts
+type runController struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the run in flight stopped
+}
+
+var runs = &runController{}
+
+// restart stops the run in flight, then starts run in the background unless prepare fails.
+// prepare is called once the previous run stopped, so it can print as well.
+func (c *runController) restart(prepare func() error, run func(ctx context.Context)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopLocked()
+	if err := prepare(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	c.cancel, c.done = cancel, done
+	go func() {
+		defer close(done)
+		defer cancel()
+		run(ctx)
+	}()
+	return nil
+}
+
+// locked calls f while no run is being prepared, for the config the preparation reads
+func (c *runController) locked(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f()
+}
+
+// stop cancels the run in flight and waits for it to stop
+func (c *runController) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopLocked()
+}
+
+func (c *runController) stopLocked() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	<-c.done
+	c.cancel, c.done = nil, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunControllerRestart(t *testing.T) {
+	received := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	files := map[string]string{
+		"a.http": fmt.Sprintf("GET %[1]s/slow HTTP/1.1\n###\nGET %[1]s/slow HTTP/1.1", server.URL),
+	}
+	config := &Config{HTTPRequestTimeout: 10000}
+	httpFileContent, err := writeHTTPFiles(t, config, files)
+	if err != nil {
+		t.Fatalf("got error on function processHTTPFiles: %v", err)
+	}
+
+	controller := &runController{}
+	var first runSummary
+	var firstStopped atomic.Bool
+	controller.restart(func() error { return nil }, func(ctx context.Context) {
+		first = sendRequests(ctx, httpFileContent, config)
+		firstStopped.Store(true)
+	})
+	<-received
+
+	start := time.Now()
+	var startedAfterFirst bool
+	err = controller.restart(func() error {
+		startedAfterFirst = firstStopped.Load()
+		return nil
+	}, func(ctx context.Context) {})
+	if err != nil {
+		t.Fatalf("got error on function restart: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the first run took %s to stop", elapsed)
+	}
+	if !startedAfterFirst {
+		t.Errorf("the second run started before the first one stopped")
+	}
+	first.Duration = 0
+	if first != (runSummary{Cancelled: true}) {
+		t.Errorf("Incorrect summary of the cancelled run: %+v", first)
+	}
+
+	if err := controller.restart(func() error { return fmt.Errorf("parse error") }, func(ctx context.Context) {
+		t.Errorf("a run shouldn't start when prepare fails")
+	}); err == nil {
+		t.Errorf("expected the error of prepare")
+	}
+	controller.stop()
+}
+
+func TestEnvCommandWhileReloading(t *testing.T) {
+	files := map[string]string{
+		"api.http":             "GET {{host}}/health HTTP/1.1",
+		"http-client.env.json": `{"dev": {"host": "http://127.0.0.1:1"}, "staging": {"host": "http://127.0.0.1:2"}}`,
+	}
+	config := &Config{HTTPRequestTimeout: 100, Environment: "dev"}
+	if _, err := writeHTTPFiles(t, config, files); err != nil {
+		t.Fatalf("got error on function processHTTPFiles: %v", err)
+	}
+	defer runs.stop()
+
+	var wg sync.WaitGroup
+	for i := range 9 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch i % 3 {
+			case 0:
+				reloadAndSend(config, nil)
+			case 1:
+				runCommand(config, "env staging")
+			default:
+				runCommand(config, "env")
+			}
+		}()
+	}
+	wg.Wait()
+	runCommand(config, "env missing")
+	runs.stop()
+
+	var environment string
+	runs.locked(func() { environment = config.Environment })
+	if environment != "staging" {
+		t.Errorf("an unknown environment should be rolled back, Got: %q", config.Environment)
+	}
+}